@@ -0,0 +1,107 @@
+package cbor
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/ugorji/go/codec"
+)
+
+// Limits of decoding CBOR from clients. A few bytes can declare a collection of 2^64 elements, a string chunk of 4GB
+// or deeply nested collections, which github.com/ugorji/go/codec would allocate memory for before reading them.
+const (
+	// MaxDepth is the maximum nesting of arrays, maps and tags, which leaves room for nested extension outputs.
+	MaxDepth = 16
+	// maxInitLen is the maximum number of elements allocated for a collection before they are actually decoded.
+	maxInitLen = 16
+)
+
+// Major types of CBOR which have a length or nested items, see Section 3.1 of [RFC8949]. The major type is the
+// high-order 3 bits of the initial byte.
+const (
+	majorTypeBytes = 2
+	majorTypeText  = 3
+	majorTypeArray = 4
+	majorTypeMap   = 5
+	majorTypeTag   = 6
+)
+
+// additionalInfoIndefinite is the additional information of indefinite-length items and the "break" stop code.
+const additionalInfoIndefinite = 31
+
+// NewHandle returns a handle to decode data items which ItemLength accepts.
+func NewHandle() *codec.CborHandle {
+	h := &codec.CborHandle{}
+	h.MaxInitLen = maxInitLen
+	h.MaxDepth = MaxDepth
+	return h
+}
+
+// ItemLength returns the byte length of the data item at the head of buf. The data item has to be well-formed and
+// encoded in definite lengths, as required by the CTAP2 canonical CBOR encoding form, every length has to fit in buf,
+// and the item must not nest deeper than MaxDepth.
+func ItemLength(buf []byte) (int, error) {
+	return itemLength(buf, 0, 0)
+}
+
+// itemLength returns the offset in buf next to the data item at offset, which is nested depth deep.
+func itemLength(buf []byte, offset, depth int) (int, error) {
+	if depth > MaxDepth {
+		return 0, errors.New(fmt.Sprintf("CBOR data item is nested deeper than %d", MaxDepth))
+	}
+	if offset >= len(buf) {
+		return 0, errors.New("CBOR data item is truncated")
+	}
+	major, info := buf[offset]>>5, buf[offset]&0x1f
+	offset++
+
+	var arg uint64
+	switch {
+	case info < 24:
+		arg = uint64(info)
+	case info <= 27:
+		size := 1 << (info - 24)
+		if len(buf)-offset < size {
+			return 0, errors.New("CBOR data item is truncated")
+		}
+		for _, b := range buf[offset : offset+size] {
+			arg = arg<<8 | uint64(b)
+		}
+		offset += size
+	case info == additionalInfoIndefinite:
+		return 0, errors.New("CBOR data item has an indefinite length")
+	default:
+		return 0, errors.New(fmt.Sprintf("CBOR data item has reserved additional information: %d", info))
+	}
+
+	rest := uint64(len(buf) - offset)
+	switch major {
+	case majorTypeBytes, majorTypeText:
+		if arg > rest {
+			return 0, errors.New(fmt.Sprintf("CBOR string of %d bytes is truncated", arg))
+		}
+		return offset + int(arg), nil
+	case majorTypeArray, majorTypeMap:
+		// Every element takes a byte at least.
+		n := arg
+		if major == majorTypeMap {
+			if n > rest/2 {
+				return 0, errors.New(fmt.Sprintf("CBOR map of %d pairs is truncated", arg))
+			}
+			n *= 2
+		}
+		if n > rest {
+			return 0, errors.New(fmt.Sprintf("CBOR array of %d elements is truncated", arg))
+		}
+		var err error
+		for i := uint64(0); i < n; i++ {
+			if offset, err = itemLength(buf, offset, depth+1); err != nil {
+				return 0, err
+			}
+		}
+		return offset, nil
+	case majorTypeTag:
+		return itemLength(buf, offset, depth+1)
+	default:
+		return offset, nil
+	}
+}
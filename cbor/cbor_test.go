@@ -0,0 +1,73 @@
+package cbor
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ugorji/go/codec"
+)
+
+func TestItemLength(t *testing.T) {
+	var encoded []byte
+	err := codec.NewEncoderBytes(&encoded, &codec.CborHandle{}).Encode(map[string]interface{}{
+		"fmt":      "packed",
+		"authData": make([]byte, 300),
+		"attStmt":  map[string]interface{}{"alg": -7, "x5c": []interface{}{[]byte{1, 2, 3}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		buf  []byte
+		want int
+	}{
+		{name: "unsigned integer", buf: []byte{0x17}, want: 1},
+		{name: "negative integer of 8 bytes", buf: []byte{0x3b, 1, 2, 3, 4, 5, 6, 7, 8}, want: 9},
+		{name: "float", buf: []byte{0xfa, 0x47, 0xc3, 0x50, 0x00}, want: 5},
+		{name: "null followed by leftover", buf: []byte{0xf6, 0x00}, want: 1},
+		{name: "tagged string", buf: []byte{0xc2, 0x42, 0x01, 0x00}, want: 4},
+		{name: "attestation object", buf: encoded, want: len(encoded)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ItemLength(tt.buf)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestItemLengthRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		buf  []byte
+	}{
+		{name: "empty", buf: nil},
+		{name: "truncated argument", buf: []byte{0x19, 0x01}},
+		{name: "truncated string", buf: []byte{0x43, 0x01, 0x02}},
+		{name: "huge string", buf: []byte{0x5a, 0xfe, 0x20, 0xad, 0x74, 0x20}},
+		{name: "huge array", buf: []byte{0x9b, 0x08, 0x08, 0x08, 0x08, 0x08, 0x08, 0x08, 0x08, 0x00}},
+		{name: "huge map", buf: []byte{0xba, 0x08, 0x08, 0x08, 0x08, 0x00, 0x00}},
+		{name: "indefinite-length string", buf: []byte{0x7f, 0x7a, 0xfe, 0x20, 0xad, 0x74, 0x20}},
+		{name: "indefinite-length map", buf: []byte{0xbf, 0x00, 0x00, 0xff}},
+		{name: "break", buf: []byte{0xff}},
+		{name: "reserved additional information", buf: []byte{0x1c}},
+		{name: "too deep", buf: append(bytes.Repeat([]byte{0x81}, MaxDepth+1), 0x00)},
+		{name: "too deep tags", buf: append(bytes.Repeat([]byte{0xc6}, MaxDepth+1), 0x00)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ItemLength(tt.buf); err == nil {
+				t.Error("expected an error, but got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// CrossOriginPolicy is the policy of the ceremonies run in iframes which are not same-origin with their ancestors.
+type CrossOriginPolicy string
+
+const (
+	// CrossOriginPolicyReject rejects every ceremony run in a cross-origin iframe.
+	CrossOriginPolicyReject CrossOriginPolicy = "reject"
+	// CrossOriginPolicyAllowlist accepts the ceremonies run in cross-origin iframes which are embedded in the pages of
+	// TopOrigins. The client has to report the origin of the page as topOrigin.
+	CrossOriginPolicyAllowlist CrossOriginPolicy = "allowlist"
+)
+
+// CheckOrigin returns an error unless the client data collected on origin is accepted. origin is accepted if
+//   - it is one of Origins,
+//   - it is a https origin whose host is the RP ID or a subdomain of it, and AllowSubdomains is set, or
+//   - it is one of RelatedOrigins, which are the origins of other domains listed in /.well-known/webauthn.
+//
+// Only https origins are accepted, except for the http origins of localhost which are configured in Origins.
+func (c RPConfig) CheckOrigin(origin string) error {
+	u, err := parseOrigin(origin)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" && !(u.Scheme == "http" && isLocalhost(u.Hostname())) {
+		return errors.New(fmt.Sprintf("origin %q is not https", origin))
+	}
+
+	for _, o := range c.Origins {
+		if sameOrigin(o, u) {
+			return nil
+		}
+	}
+	if u.Scheme == "https" && c.AllowSubdomains && isRegistrableSuffix(u.Hostname(), c.ID) {
+		return nil
+	}
+	for _, o := range c.RelatedOrigins {
+		if sameOrigin(o, u) {
+			return nil
+		}
+	}
+	return errors.New(fmt.Sprintf("origin %q is not allowed", origin))
+}
+
+// CheckCrossOrigin returns an error unless the client data collected in an iframe embedded in the page of topOrigin
+// is accepted. crossOrigin and topOrigin are the members of the client data of the same names.
+func (c RPConfig) CheckCrossOrigin(crossOrigin bool, topOrigin string) error {
+	if !crossOrigin && topOrigin == "" {
+		return nil
+	}
+	if c.CrossOrigin != CrossOriginPolicyAllowlist {
+		return errors.New("ceremonies in cross-origin iframes are not allowed")
+	}
+	if topOrigin == "" {
+		return errors.New("top origin of the cross-origin iframe is missing")
+	}
+	u, err := parseOrigin(topOrigin)
+	if err != nil {
+		return err
+	}
+	for _, o := range c.TopOrigins {
+		if sameOrigin(o, u) {
+			return nil
+		}
+	}
+	return errors.New(fmt.Sprintf("top origin %q is not allowed", topOrigin))
+}
+
+// validateOrigins returns an error if an origin is malformed, or if it is not an origin the RP ID is valid for. Only
+// localhost may be served over http.
+func (c RPConfig) validateOrigins() error {
+	if len(c.Origins) == 0 {
+		return errors.New("no origin is allowed")
+	}
+	for _, o := range c.Origins {
+		u, err := parseOrigin(o)
+		if err != nil {
+			return err
+		}
+		if u.Scheme != "https" && !(u.Scheme == "http" && isLocalhost(u.Hostname())) {
+			return errors.New(fmt.Sprintf("origin %q must be https unless it is localhost", o))
+		}
+		// The RP ID must be equal to the effective domain of the origin, or a registrable domain suffix of it.
+		if !isRegistrableSuffix(u.Hostname(), c.ID) {
+			return errors.New(fmt.Sprintf("RP ID %q is not valid for origin %q: list it in related origins instead", c.ID, o))
+		}
+	}
+	for _, o := range c.RelatedOrigins {
+		u, err := parseOrigin(o)
+		if err != nil {
+			return err
+		}
+		if u.Scheme != "https" {
+			return errors.New(fmt.Sprintf("related origin %q must be https", o))
+		}
+	}
+
+	switch c.CrossOrigin {
+	case CrossOriginPolicyReject:
+	case CrossOriginPolicyAllowlist:
+		if len(c.TopOrigins) == 0 {
+			return errors.New("no top origin is allowed for cross-origin iframes")
+		}
+	default:
+		return errors.New(fmt.Sprintf("invalid cross-origin policy: %q", c.CrossOrigin))
+	}
+	for _, o := range c.TopOrigins {
+		u, err := parseOrigin(o)
+		if err != nil {
+			return err
+		}
+		if u.Scheme != "https" && !(u.Scheme == "http" && isLocalhost(u.Hostname())) {
+			return errors.New(fmt.Sprintf("top origin %q must be https unless it is localhost", o))
+		}
+	}
+	return nil
+}
+
+// defaultPorts are the ports of the schemes which the origins of their default port are serialized without.
+var defaultPorts = map[string]string{"https": "443", "http": "80"}
+
+// parseOrigin parses an origin, i.e. a URL which has only a scheme, a host and optionally a port. The default port of
+// the scheme is removed, so that "https://example.com:443" is the same origin as "https://example.com".
+func parseOrigin(origin string) (*url.URL, error) {
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme == "" || u.Host == "" || u.User != nil || (u.Path != "" && u.Path != "/") ||
+		u.RawQuery != "" || u.Fragment != "" {
+		return nil, errors.New(fmt.Sprintf("invalid origin: %q", origin))
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); port != "" && port == defaultPorts[u.Scheme] {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+	u.Path = ""
+	return u, nil
+}
+
+// sameOrigin reports whether the configured origin o is the same origin as u.
+func sameOrigin(o string, u *url.URL) bool {
+	v, err := parseOrigin(o)
+	return err == nil && v.Scheme == u.Scheme && v.Host == u.Host
+}
+
+// isRegistrableSuffix reports whether domain is host itself or a parent domain of it, e.g. "example.com" of
+// "login.example.com".
+func isRegistrableSuffix(host, domain string) bool {
+	host, domain = strings.ToLower(host), strings.ToLower(domain)
+	if domain == "" {
+		return false
+	}
+	return host == domain || (net.ParseIP(host) == nil && strings.HasSuffix(host, "."+domain))
+}
+
+// isLocalhost reports whether host is a loopback host, which browsers treat as a secure context over http.
+func isLocalhost(host string) bool {
+	host = strings.ToLower(host)
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	rp := Default()
+	rp.ID = "example.com"
+	rp.Origins = []string{"https://example.com", "http://localhost:8080"}
+	rp.RelatedOrigins = []string{"https://example.co.jp:443"}
+
+	tests := []struct {
+		name            string
+		origin          string
+		allowSubdomains bool
+		wantErr         bool
+	}{
+		{name: "configured", origin: "https://example.com"},
+		{name: "configured in upper case", origin: "HTTPS://EXAMPLE.COM"},
+		{name: "localhost over http", origin: "http://localhost:8080"},
+		{name: "related", origin: "https://example.co.jp"},
+		{name: "default port", origin: "https://example.com:443"},
+		{name: "related with default port", origin: "https://example.co.jp:443"},
+		{name: "default port of http", origin: "http://localhost:80", wantErr: true},
+		{name: "subdomain", origin: "https://login.example.com", allowSubdomains: true},
+		{name: "subdomain with port", origin: "https://login.example.com:8443", allowSubdomains: true},
+		{name: "subdomain not allowed", origin: "https://login.example.com", wantErr: true},
+		{name: "subdomain over http", origin: "http://login.example.com", allowSubdomains: true, wantErr: true},
+		{name: "suffix which is not a subdomain", origin: "https://evilexample.com", allowSubdomains: true, wantErr: true},
+		{name: "configured over http", origin: "http://example.com", wantErr: true},
+		{name: "other port", origin: "https://example.com:8443", wantErr: true},
+		{name: "localhost on other port", origin: "http://localhost:3000", wantErr: true},
+		{name: "with path", origin: "https://example.com/login", wantErr: true},
+		{name: "empty", origin: "", wantErr: true},
+		{name: "opaque", origin: "null", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := rp
+			rp.AllowSubdomains = tt.allowSubdomains
+			err := rp.CheckOrigin(tt.origin)
+			if tt.wantErr && err == nil {
+				t.Error("expected an error, but got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateOrigins(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		origins        []string
+		relatedOrigins []string
+		wantErr        bool
+	}{
+		{name: "subdomain", id: "example.com", origins: []string{"https://login.example.com"}},
+		{name: "localhost over http", id: "localhost", origins: []string{"http://localhost:8080"}},
+		{name: "loopback address over http", id: "127.0.0.1", origins: []string{"http://127.0.0.1:8080"}},
+		{name: "over http", id: "example.com", origins: []string{"http://example.com"}, wantErr: true},
+		{name: "other domain", id: "example.com", origins: []string{"https://example.co.jp"}, wantErr: true},
+		{name: "parent domain", id: "login.example.com", origins: []string{"https://example.com"}, wantErr: true},
+		{
+			name:           "related origin over http",
+			id:             "localhost",
+			origins:        []string{"http://localhost:8080"},
+			relatedOrigins: []string{"http://localhost:3000"},
+			wantErr:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := Default()
+			rp.ID = tt.id
+			rp.Origins = tt.origins
+			rp.RelatedOrigins = tt.relatedOrigins
+			err := rp.Validate()
+			if tt.wantErr && err == nil {
+				t.Error("expected an error, but got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckCrossOrigin(t *testing.T) {
+	partner := "https://partner.example.org"
+	tests := []struct {
+		name        string
+		policy      CrossOriginPolicy
+		crossOrigin bool
+		topOrigin   string
+		wantErr     bool
+	}{
+		{name: "same-origin", policy: CrossOriginPolicyReject},
+		{name: "rejected", policy: CrossOriginPolicyReject, crossOrigin: true, topOrigin: partner, wantErr: true},
+		{name: "allowed", policy: CrossOriginPolicyAllowlist, crossOrigin: true, topOrigin: partner},
+		{
+			name:        "not listed",
+			policy:      CrossOriginPolicyAllowlist,
+			crossOrigin: true,
+			topOrigin:   "https://example.net",
+			wantErr:     true,
+		},
+		{name: "no top origin", policy: CrossOriginPolicyAllowlist, crossOrigin: true, wantErr: true},
+		{name: "top origin without cross-origin", policy: CrossOriginPolicyReject, topOrigin: partner, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := Default()
+			rp.CrossOrigin = tt.policy
+			rp.TopOrigins = []string{partner}
+			err := rp.CheckCrossOrigin(tt.crossOrigin, tt.topOrigin)
+			if tt.wantErr && err == nil {
+				t.Error("expected an error, but got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,343 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/miliya612/webauthn-demo/cose"
+	"github.com/miliya612/webauthn-demo/domain/service/attestation"
+	"github.com/miliya612/webauthn-demo/webauthnif"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables override the settings in the config file.
+const (
+	EnvRPID               = "WEBAUTHN_RP_ID"
+	EnvRPName             = "WEBAUTHN_RP_NAME"
+	EnvOrigins            = "WEBAUTHN_ORIGINS"
+	EnvAllowSubdomains    = "WEBAUTHN_ALLOW_SUBDOMAINS"
+	EnvRelatedOrigins     = "WEBAUTHN_RELATED_ORIGINS"
+	EnvCrossOrigin        = "WEBAUTHN_CROSS_ORIGIN"
+	EnvTopOrigins         = "WEBAUTHN_TOP_ORIGINS"
+	EnvTimeout            = "WEBAUTHN_TIMEOUT"
+	EnvUserVerification   = "WEBAUTHN_USER_VERIFICATION"
+	EnvAttestation        = "WEBAUTHN_ATTESTATION"
+	EnvAttestationPolicy  = "WEBAUTHN_ATTESTATION_POLICY"
+	EnvRequireResidentKey = "WEBAUTHN_REQUIRE_RESIDENT_KEY"
+	// EnvAndroidKeyAllowAllApplications, EnvAndroidKeyTEEOnly, EnvAndroidKeyRequireOriginGenerated and
+	// EnvAndroidKeyRequirePurposeSign set the fields of the AndroidKey policy.
+	EnvAndroidKeyAllowAllApplications   = "WEBAUTHN_ANDROID_KEY_ALLOW_ALL_APPLICATIONS"
+	EnvAndroidKeyTEEOnly                = "WEBAUTHN_ANDROID_KEY_TEE_ONLY"
+	EnvAndroidKeyRequireOriginGenerated = "WEBAUTHN_ANDROID_KEY_REQUIRE_ORIGIN_GENERATED"
+	EnvAndroidKeyRequirePurposeSign     = "WEBAUTHN_ANDROID_KEY_REQUIRE_PURPOSE_SIGN"
+	EnvAndroidSafetyNetRoots            = "WEBAUTHN_ANDROID_SAFETYNET_ROOTS"
+	EnvAndroidSafetyNetMaxTimestampSkew = "WEBAUTHN_ANDROID_SAFETYNET_MAX_TIMESTAMP_SKEW"
+	// EnvAlgorithms is a comma separated list of algorithm names, e.g. "ES256,RS256".
+	EnvAlgorithms = "WEBAUTHN_ALGORITHMS"
+)
+
+// RPConfig is the Relying Party settings of the registration and authentication ceremonies.
+type RPConfig struct {
+	// ID is the RP ID, which is the domain credentials are scoped to, e.g. "example.com".
+	ID string `yaml:"id" json:"id"`
+	// Name is the human-palatable name of the Relying Party, intended only for display.
+	Name string `yaml:"name" json:"name"`
+	// Origins are the origins the client data is accepted from, e.g. "https://login.example.com". The RP ID has to be
+	// their host or a parent domain of it. Only localhost may be served over http.
+	Origins []string `yaml:"origins" json:"origins"`
+	// AllowSubdomains accepts the client data from every https origin whose host is a subdomain of the RP ID.
+	AllowSubdomains bool `yaml:"allowSubdomains" json:"allowSubdomains"`
+	// RelatedOrigins are the https origins of other domains which the credentials of the RP ID may be used on, see
+	// "Related Origin Requests" of WebAuthn Level 3. They are served at /.well-known/webauthn, at the root of the hosts
+	// of a tenant with a path prefix as well.
+	RelatedOrigins []string `yaml:"relatedOrigins" json:"relatedOrigins"`
+	// CrossOrigin is the policy of the ceremonies run in iframes embedded in pages of other origins.
+	CrossOrigin CrossOriginPolicy `yaml:"crossOrigin" json:"crossOrigin"`
+	// TopOrigins are the origins of the pages the iframes may be embedded in if CrossOrigin is
+	// CrossOriginPolicyAllowlist, e.g. "https://partner.example.org".
+	TopOrigins []string `yaml:"topOrigins" json:"topOrigins"`
+	// Timeout is the time in milliseconds the Relying Party is willing to wait for a call to complete.
+	Timeout uint32 `yaml:"timeout" json:"timeout"`
+	// UserVerification is the user verification requirement. The UV flag is verified only if it is "required".
+	UserVerification webauthnif.UserVerificationRequirement `yaml:"userVerification" json:"userVerification"`
+	// Attestation is the attestation conveyance preference for registration.
+	Attestation webauthnif.AttestationConveyancePreference `yaml:"attestation" json:"attestation"`
+	// AttestationPolicy is the attestation trustworthiness new credentials must have.
+	AttestationPolicy attestation.Policy `yaml:"attestationPolicy" json:"attestationPolicy"`
+	// AndroidKey is the policy on the authorization lists of android-key attestation statements.
+	AndroidKey attestation.AndroidKeyPolicy `yaml:"androidKey" json:"androidKey"`
+	// AndroidSafetyNet is the settings to verify SafetyNet responses of android-safetynet attestation statements.
+	AndroidSafetyNet AndroidSafetyNetConfig `yaml:"androidSafetyNet" json:"androidSafetyNet"`
+	// RequireResidentKey requires client-side-resident credentials for registration.
+	RequireResidentKey bool `yaml:"requireResidentKey" json:"requireResidentKey"`
+	// Algorithms are the credential algorithms offered as pubKeyCredParams, in the order of preference.
+	Algorithms Algorithms `yaml:"algorithms" json:"algorithms"`
+}
+
+// AndroidSafetyNetConfig is the settings to verify SafetyNet responses.
+type AndroidSafetyNetConfig struct {
+	// Roots is the path to the PEM encoded root certificates SafetyNet responses have to chain up to. The system
+	// roots are used if it is empty.
+	Roots string `yaml:"roots" json:"roots"`
+	// MaxTimestampSkew is the maximum difference in milliseconds between timestampMs of SafetyNet responses and the
+	// current time.
+	MaxTimestampSkew uint32 `yaml:"maxTimestampSkew" json:"maxTimestampSkew"`
+}
+
+// Default returns the settings of the demo, which is served at http://localhost:8080.
+func Default() RPConfig {
+	return RPConfig{
+		ID:                "localhost",
+		Name:              "miliya612 - webauthn demo",
+		Origins:           []string{"http://localhost:8080"},
+		Timeout:           6000,
+		UserVerification:  webauthnif.UserVerificationRequirementPreferred,
+		Attestation:       webauthnif.AttestationConveyancePreferenceDirect,
+		AttestationPolicy: attestation.PolicyAcceptNone,
+		AndroidKey:        attestation.DefaultAndroidKeyPolicy,
+		AndroidSafetyNet: AndroidSafetyNetConfig{
+			MaxTimestampSkew: uint32(attestation.DefaultAndroidSafetyNetConfig.MaxTimestampSkew / time.Millisecond),
+		},
+		// Every algorithm whose signatures can be verified, in the order of preference.
+		Algorithms: Algorithms{
+			webauthnif.COSEAlgorithmIdentifierES256,
+			webauthnif.COSEAlgorithmIdentifierEdDSA,
+			webauthnif.COSEAlgorithmIdentifierES384,
+			webauthnif.COSEAlgorithmIdentifierES512,
+			webauthnif.COSEAlgorithmIdentifierPS256,
+			webauthnif.COSEAlgorithmIdentifierPS384,
+			webauthnif.COSEAlgorithmIdentifierPS512,
+			webauthnif.COSEAlgorithmIdentifierRS256,
+			webauthnif.COSEAlgorithmIdentifierRS384,
+			webauthnif.COSEAlgorithmIdentifierRS512,
+			webauthnif.COSEAlgorithmIdentifierES256K,
+		},
+		CrossOrigin: CrossOriginPolicyReject,
+	}
+}
+
+// Load reads the settings in the file at path over Default(), and then the environment variables over them. The file
+// is parsed as JSON if its extension is ".json", and as YAML otherwise. No file is read if path is empty.
+func Load(path string) (*RPConfig, error) {
+	c := Default()
+	if path != "" {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if filepath.Ext(path) == ".json" {
+			err = json.Unmarshal(b, &c)
+		} else {
+			err = yaml.Unmarshal(b, &c)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("unable to parse %v", path))
+		}
+	}
+
+	if err := c.applyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// applyEnv overwrites the settings with the environment variables lookup finds.
+func (c *RPConfig) applyEnv(lookup func(key string) (string, bool)) error {
+	if v, ok := lookup(EnvRPID); ok {
+		c.ID = v
+	}
+	if v, ok := lookup(EnvRPName); ok {
+		c.Name = v
+	}
+	if v, ok := lookup(EnvOrigins); ok {
+		c.Origins = splitList(v)
+	}
+	if v, ok := lookup(EnvAllowSubdomains); ok {
+		allowed, err := strconv.ParseBool(v)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("invalid %v", EnvAllowSubdomains))
+		}
+		c.AllowSubdomains = allowed
+	}
+	if v, ok := lookup(EnvRelatedOrigins); ok {
+		c.RelatedOrigins = splitList(v)
+	}
+	if v, ok := lookup(EnvCrossOrigin); ok {
+		c.CrossOrigin = CrossOriginPolicy(v)
+	}
+	if v, ok := lookup(EnvTopOrigins); ok {
+		c.TopOrigins = splitList(v)
+	}
+	if v, ok := lookup(EnvTimeout); ok {
+		timeout, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("invalid %v", EnvTimeout))
+		}
+		c.Timeout = uint32(timeout)
+	}
+	if v, ok := lookup(EnvUserVerification); ok {
+		c.UserVerification = webauthnif.UserVerificationRequirement(v)
+	}
+	if v, ok := lookup(EnvAttestation); ok {
+		c.Attestation = webauthnif.AttestationConveyancePreference(v)
+	}
+	if v, ok := lookup(EnvAttestationPolicy); ok {
+		c.AttestationPolicy = attestation.Policy(v)
+	}
+	for _, f := range []struct {
+		env   string
+		field *bool
+	}{
+		{EnvAndroidKeyAllowAllApplications, &c.AndroidKey.AllowAllApplications},
+		{EnvAndroidKeyTEEOnly, &c.AndroidKey.TEEOnly},
+		{EnvAndroidKeyRequireOriginGenerated, &c.AndroidKey.RequireOriginGenerated},
+		{EnvAndroidKeyRequirePurposeSign, &c.AndroidKey.RequirePurposeSign},
+	} {
+		if v, ok := lookup(f.env); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return errors.Wrap(err, fmt.Sprintf("invalid %v", f.env))
+			}
+			*f.field = b
+		}
+	}
+	if v, ok := lookup(EnvAndroidSafetyNetRoots); ok {
+		c.AndroidSafetyNet.Roots = v
+	}
+	if v, ok := lookup(EnvAndroidSafetyNetMaxTimestampSkew); ok {
+		skew, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("invalid %v", EnvAndroidSafetyNetMaxTimestampSkew))
+		}
+		c.AndroidSafetyNet.MaxTimestampSkew = uint32(skew)
+	}
+	if v, ok := lookup(EnvRequireResidentKey); ok {
+		required, err := strconv.ParseBool(v)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("invalid %v", EnvRequireResidentKey))
+		}
+		c.RequireResidentKey = required
+	}
+	if v, ok := lookup(EnvAlgorithms); ok {
+		algs, err := parseAlgorithms(splitList(v))
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("invalid %v", EnvAlgorithms))
+		}
+		c.Algorithms = algs
+	}
+	return nil
+}
+
+// Validate returns an error if a setting is missing or malformed.
+func (c RPConfig) Validate() error {
+	if c.ID == "" {
+		return errors.New("RP ID is missing")
+	}
+	if c.Name == "" {
+		return errors.New("RP name is missing")
+	}
+	if err := c.validateOrigins(); err != nil {
+		return err
+	}
+	if c.Timeout == 0 {
+		return errors.New("timeout must be positive")
+	}
+	switch c.UserVerification {
+	case webauthnif.UserVerificationRequirementRequired,
+		webauthnif.UserVerificationRequirementPreferred,
+		webauthnif.UserVerificationRequirementDiscouraged:
+	default:
+		return errors.New(fmt.Sprintf("invalid user verification requirement: %q", c.UserVerification))
+	}
+	switch c.Attestation {
+	case webauthnif.AttestationConveyancePreferenceNone,
+		webauthnif.AttestationConveyancePreferenceIndirect,
+		webauthnif.AttestationConveyancePreferenceDirect:
+	default:
+		return errors.New(fmt.Sprintf("invalid attestation conveyance preference: %q", c.Attestation))
+	}
+	switch c.AttestationPolicy {
+	case attestation.PolicyAcceptNone, attestation.PolicyRequireSelf, attestation.PolicyRequireFull:
+	default:
+		return errors.New(fmt.Sprintf("invalid attestation policy: %q", c.AttestationPolicy))
+	}
+	if c.AndroidSafetyNet.MaxTimestampSkew == 0 {
+		return errors.New("maxTimestampSkew of androidSafetyNet must be positive")
+	}
+	if len(c.Algorithms) == 0 {
+		return errors.New("no algorithm is offered")
+	}
+	for _, alg := range c.Algorithms {
+		if !cose.IsSupported(cose.Algorithm(alg)) {
+			return errors.New(fmt.Sprintf("unsupported algorithm: %d", alg))
+		}
+	}
+	return nil
+}
+
+// UserVerificationRequired reports whether the UV flag has to be set in authenticator data.
+func (c RPConfig) UserVerificationRequired() bool {
+	return c.UserVerification == webauthnif.UserVerificationRequirementRequired
+}
+
+// Algorithms is a list of credential algorithms, which is written as algorithm names, e.g. ["ES256", "RS256"], in
+// config files.
+type Algorithms []webauthnif.COSEAlgorithmIdentifier
+
+func (a *Algorithms) UnmarshalYAML(value *yaml.Node) error {
+	var names []string
+	if err := value.Decode(&names); err != nil {
+		return err
+	}
+	algs, err := parseAlgorithms(names)
+	if err != nil {
+		return err
+	}
+	*a = algs
+	return nil
+}
+
+func (a *Algorithms) UnmarshalJSON(b []byte) error {
+	var names []string
+	if err := json.Unmarshal(b, &names); err != nil {
+		return err
+	}
+	algs, err := parseAlgorithms(names)
+	if err != nil {
+		return err
+	}
+	*a = algs
+	return nil
+}
+
+func parseAlgorithms(names []string) (Algorithms, error) {
+	algs := Algorithms{}
+	for _, name := range names {
+		alg, err := cose.ParseAlgorithm(name)
+		if err != nil {
+			return nil, err
+		}
+		algs = append(algs, webauthnif.COSEAlgorithmIdentifier(alg))
+	}
+	return algs, nil
+}
+
+// splitList splits a comma separated list, ignoring spaces around the items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
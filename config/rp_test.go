@@ -0,0 +1,122 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/miliya612/webauthn-demo/webauthnif"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	want := Default()
+	want.ID = "example.com"
+	want.Origins = []string{"https://example.com", "https://login.example.com"}
+	want.UserVerification = webauthnif.UserVerificationRequirementRequired
+	want.Algorithms = Algorithms{webauthnif.COSEAlgorithmIdentifierES256, webauthnif.COSEAlgorithmIdentifierRS256}
+	want.AndroidKey.TEEOnly = true
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name: "YAML",
+			file: "config.yaml",
+			content: `id: example.com
+origins:
+  - https://example.com
+  - https://login.example.com
+userVerification: required
+algorithms: [ES256, RS256]
+androidKey:
+  teeOnly: true
+`,
+		},
+		{
+			name: "JSON",
+			file: "config.json",
+			content: `{
+  "id": "example.com",
+  "origins": ["https://example.com", "https://login.example.com"],
+  "userVerification": "required",
+  "algorithms": ["ES256", "RS256"],
+  "androidKey": {"teeOnly": true}
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Load(writeFile(t, tt.file, tt.content))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(*got, want) {
+				t.Errorf("got %+v, want %+v", *got, want)
+			}
+		})
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	path := writeFile(t, "config.yaml", "id: example.com\ntimeout: 30000\n")
+	t.Setenv(EnvRPID, "example.org")
+	t.Setenv(EnvOrigins, "https://example.org, https://www.example.org")
+	t.Setenv(EnvRequireResidentKey, "true")
+	t.Setenv(EnvAlgorithms, "EdDSA,ES256")
+	t.Setenv(EnvAndroidKeyAllowAllApplications, "true")
+	t.Setenv(EnvAndroidSafetyNetRoots, "/etc/webauthn/safetynet.pem")
+	t.Setenv(EnvAndroidSafetyNetMaxTimestampSkew, "30000")
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := Default()
+	want.ID = "example.org"
+	want.Origins = []string{"https://example.org", "https://www.example.org"}
+	want.Timeout = 30000
+	want.RequireResidentKey = true
+	want.Algorithms = Algorithms{webauthnif.COSEAlgorithmIdentifierEdDSA, webauthnif.COSEAlgorithmIdentifierES256}
+	want.AndroidKey.AllowAllApplications = true
+	want.AndroidSafetyNet = AndroidSafetyNetConfig{Roots: "/etc/webauthn/safetynet.pem", MaxTimestampSkew: 30000}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("got %+v, want %+v", *got, want)
+	}
+}
+
+func TestLoadRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "unknown algorithm", content: "algorithms: [HS256]\n"},
+		{name: "no origin", content: "origins: []\n"},
+		{name: "origin with path", content: "origins: [https://example.com/login]\n"},
+		{name: "unknown user verification requirement", content: "userVerification: always\n"},
+		{name: "unknown attestation conveyance preference", content: "attestation: full\n"},
+		{name: "empty RP ID", content: "id: \"\"\n"},
+		{name: "no timestamp skew of SafetyNet", content: "androidSafetyNet:\n  maxTimestampSkew: 0\n"},
+		{name: "malformed", content: "id: [example.com\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(writeFile(t, "config.yaml", tt.content)); err == nil {
+				t.Error("expected an error, but got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultTenantID is the ID of the only tenant of a config file which has no tenants, i.e. a single Relying Party.
+const DefaultTenantID = "default"
+
+// Tenant is a Relying Party hosted by the server. Users, credentials and sessions are partitioned by TenantID.
+// Requests are routed to a tenant by the Host header if Hosts is not empty, and by the path prefix if PathPrefix is
+// not empty. A tenant with neither receives every request no other tenant does.
+type Tenant struct {
+	// TenantID identifies the tenant.
+	TenantID string `yaml:"tenant" json:"tenant"`
+	// Hosts are the host names requests to the tenant are sent to, e.g. "login.example.com".
+	Hosts []string `yaml:"hosts" json:"hosts"`
+	// PathPrefix is the path the endpoints of the tenant are under, e.g. "/example".
+	PathPrefix string `yaml:"pathPrefix" json:"pathPrefix"`
+	// RPConfig is the Relying Party settings of the tenant, which is written inline in config files.
+	RPConfig `yaml:",inline"`
+}
+
+// tenantFields is Tenant without its methods, to decode the fields of Tenant by default.
+type tenantFields Tenant
+
+// UnmarshalYAML decodes the settings of a tenant over Default().
+func (t *Tenant) UnmarshalYAML(value *yaml.Node) error {
+	fields := tenantFields{RPConfig: Default()}
+	if err := value.Decode(&fields); err != nil {
+		return err
+	}
+	*t = Tenant(fields)
+	return nil
+}
+
+// UnmarshalJSON decodes the settings of a tenant over Default().
+func (t *Tenant) UnmarshalJSON(b []byte) error {
+	fields := tenantFields{RPConfig: Default()}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+	*t = Tenant(fields)
+	return nil
+}
+
+// LoadTenants reads the tenants in the file at path. A file which has no "tenants" is read as a single Relying Party
+// by Load, and results in the tenant DefaultTenantID, which receives every request. The environment variables
+// override the settings of such a single Relying Party only.
+func LoadTenants(path string) ([]Tenant, error) {
+	var file struct {
+		Tenants []Tenant `yaml:"tenants" json:"tenants"`
+	}
+	if path != "" {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if filepath.Ext(path) == ".json" {
+			err = json.Unmarshal(b, &file)
+		} else {
+			err = yaml.Unmarshal(b, &file)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("unable to parse %v", path))
+		}
+	}
+
+	if len(file.Tenants) == 0 {
+		rp, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		return []Tenant{{TenantID: DefaultTenantID, RPConfig: *rp}}, nil
+	}
+	if err := ValidateTenants(file.Tenants); err != nil {
+		return nil, err
+	}
+	return file.Tenants, nil
+}
+
+// ValidateTenants returns an error if the settings of a tenant are invalid, or if two tenants would receive the same
+// requests.
+func ValidateTenants(tenants []Tenant) error {
+	ids := map[string]bool{}
+	routes := map[string]string{}
+	for _, t := range tenants {
+		if t.TenantID == "" {
+			return errors.New("tenant ID is missing")
+		}
+		if ids[t.TenantID] {
+			return errors.New(fmt.Sprintf("tenant %q is duplicated", t.TenantID))
+		}
+		ids[t.TenantID] = true
+
+		if t.PathPrefix != "" && (!strings.HasPrefix(t.PathPrefix, "/") || strings.HasSuffix(t.PathPrefix, "/")) {
+			return errors.New(fmt.Sprintf("tenant %q: path prefix must start with \"/\" and must not end with \"/\": %q",
+				t.TenantID, t.PathPrefix))
+		}
+		hosts := t.Hosts
+		if len(hosts) == 0 {
+			hosts = []string{""}
+		}
+		for _, h := range hosts {
+			route := strings.ToLower(h) + t.PathPrefix
+			if other, ok := routes[route]; ok {
+				return errors.New(fmt.Sprintf("tenants %q and %q receive the same requests", other, t.TenantID))
+			}
+			routes[route] = t.TenantID
+		}
+
+		// The client fetches the related origins from the root of the RP ID, which a tenant routed by its path prefix
+		// only does not receive.
+		if t.PathPrefix != "" && len(t.Hosts) == 0 && len(t.RelatedOrigins) != 0 {
+			return errors.New(fmt.Sprintf("tenant %q: related origins require hosts to be served at their roots",
+				t.TenantID))
+		}
+
+		if err := t.RPConfig.Validate(); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("tenant %q", t.TenantID))
+		}
+	}
+	return nil
+}
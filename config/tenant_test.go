@@ -0,0 +1,81 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/miliya612/webauthn-demo/domain/service/attestation"
+)
+
+func TestLoadTenants(t *testing.T) {
+	path := writeFile(t, "config.yaml", `tenants:
+  - tenant: example
+    hosts: [login.example.com]
+    id: example.com
+    origins: [https://login.example.com]
+    attestationPolicy: full
+  - tenant: demo
+    pathPrefix: /demo
+`)
+	got, err := LoadTenants(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	example := Default()
+	example.ID = "example.com"
+	example.Origins = []string{"https://login.example.com"}
+	example.AttestationPolicy = attestation.PolicyRequireFull
+	want := []Tenant{
+		{TenantID: "example", Hosts: []string{"login.example.com"}, RPConfig: example},
+		{TenantID: "demo", PathPrefix: "/demo", RPConfig: Default()},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadTenantsOfSingleRP(t *testing.T) {
+	path := writeFile(t, "config.json", `{"id": "example.com", "origins": ["https://example.com"]}`)
+	t.Setenv(EnvRPName, "Example")
+
+	got, err := LoadTenants(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rp := Default()
+	rp.ID = "example.com"
+	rp.Name = "Example"
+	rp.Origins = []string{"https://example.com"}
+	want := []Tenant{{TenantID: DefaultTenantID, RPConfig: rp}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadTenantsRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "no tenant ID", content: "tenants: [{hosts: [example.com]}]\n"},
+		{name: "duplicated tenant ID", content: "tenants: [{tenant: a, hosts: [a.com]}, {tenant: a, hosts: [b.com]}]\n"},
+		{name: "same host", content: "tenants: [{tenant: a, hosts: [a.com]}, {tenant: b, hosts: [A.com]}]\n"},
+		{name: "two catch-all tenants", content: "tenants: [{tenant: a}, {tenant: b}]\n"},
+		{name: "prefix without slash", content: "tenants: [{tenant: a, pathPrefix: a}]\n"},
+		{name: "prefix with trailing slash", content: "tenants: [{tenant: a, pathPrefix: /a/}]\n"},
+		{
+			name:    "related origins without hosts",
+			content: "tenants: [{tenant: a, pathPrefix: /a, relatedOrigins: [\"https://example.co.jp\"]}]\n",
+		},
+		{name: "unknown attestation policy", content: "tenants: [{tenant: a, attestationPolicy: basic}]\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadTenants(writeFile(t, "config.yaml", tt.content)); err == nil {
+				t.Error("expected an error, but got nil")
+			}
+		})
+	}
+}
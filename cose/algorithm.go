@@ -0,0 +1,145 @@
+package cose
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	_ "crypto/sha256" // registers crypto.SHA256
+	_ "crypto/sha512" // registers crypto.SHA384 and crypto.SHA512
+	"fmt"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secp256k1ecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/pkg/errors"
+)
+
+// Algorithm is the COSE algorithm identifier, see https://www.iana.org/assignments/cose/cose.xhtml#algorithms
+type Algorithm int
+
+const (
+	AlgES256  Algorithm = -7
+	AlgEdDSA  Algorithm = -8
+	AlgES384  Algorithm = -35
+	AlgES512  Algorithm = -36
+	AlgPS256  Algorithm = -37
+	AlgPS384  Algorithm = -38
+	AlgPS512  Algorithm = -39
+	AlgES256K Algorithm = -47
+	AlgRS256  Algorithm = -257
+	AlgRS384  Algorithm = -258
+	AlgRS512  Algorithm = -259
+)
+
+// algorithmParams is the key type and curve an algorithm is used with, and how its signatures are verified.
+type algorithmParams struct {
+	keyType KeyType
+	// curve is zero for algorithms which are used with RSA keys.
+	curve Curve
+	// hash is zero for algorithms which sign data itself rather than its digest.
+	hash   crypto.Hash
+	verify func(pubKey crypto.PublicKey, hash crypto.Hash, digest, sig []byte) error
+}
+
+var algorithms = map[Algorithm]algorithmParams{
+	AlgES256:  {keyType: KeyTypeEC2, curve: CurveP256, hash: crypto.SHA256, verify: verifyECDSA},
+	AlgES384:  {keyType: KeyTypeEC2, curve: CurveP384, hash: crypto.SHA384, verify: verifyECDSA},
+	AlgES512:  {keyType: KeyTypeEC2, curve: CurveP521, hash: crypto.SHA512, verify: verifyECDSA},
+	AlgES256K: {keyType: KeyTypeEC2, curve: CurveSecp256k1, hash: crypto.SHA256, verify: verifySecp256k1},
+	AlgEdDSA:  {keyType: KeyTypeOKP, curve: CurveEd25519, verify: verifyEdDSA},
+	AlgPS256:  {keyType: KeyTypeRSA, hash: crypto.SHA256, verify: verifyRSAPSS},
+	AlgPS384:  {keyType: KeyTypeRSA, hash: crypto.SHA384, verify: verifyRSAPSS},
+	AlgPS512:  {keyType: KeyTypeRSA, hash: crypto.SHA512, verify: verifyRSAPSS},
+	AlgRS256:  {keyType: KeyTypeRSA, hash: crypto.SHA256, verify: verifyRSAPKCS1v15},
+	AlgRS384:  {keyType: KeyTypeRSA, hash: crypto.SHA384, verify: verifyRSAPKCS1v15},
+	AlgRS512:  {keyType: KeyTypeRSA, hash: crypto.SHA512, verify: verifyRSAPKCS1v15},
+}
+
+// IsSupported reports whether signatures made by alg can be verified.
+func IsSupported(alg Algorithm) bool {
+	_, ok := algorithms[alg]
+	return ok
+}
+
+// HashFunc returns the hash function alg signs digests of. It returns zero for EdDSA, which signs data itself.
+func HashFunc(alg Algorithm) (crypto.Hash, error) {
+	params, ok := algorithms[alg]
+	if !ok {
+		return 0, errors.New(fmt.Sprintf("unsupported algorithm: %d", alg))
+	}
+	return params.hash, nil
+}
+
+// ParseAlgorithm returns the supported algorithm whose name in the JSON Web Algorithms registry is name, e.g. "ES256".
+func ParseAlgorithm(name string) (Algorithm, error) {
+	for alg, jwa := range jwaAlgorithms {
+		if jwa == name && IsSupported(alg) {
+			return alg, nil
+		}
+	}
+	return 0, errors.New(fmt.Sprintf("unsupported algorithm: %q", name))
+}
+
+// verify verifies that sig is a signature over data made with key by its algorithm.
+func verify(key Key, data, sig []byte) error {
+	params, ok := algorithms[key.Algorithm()]
+	if !ok {
+		return errors.New(fmt.Sprintf("unsupported algorithm: %d", key.Algorithm()))
+	}
+	pubKey, err := key.PublicKey()
+	if err != nil {
+		return err
+	}
+	digest := data
+	if params.hash != 0 {
+		h := params.hash.New()
+		h.Write(data)
+		digest = h.Sum(nil)
+	}
+	return params.verify(pubKey, params.hash, digest, sig)
+}
+
+// verifyECDSA verifies an ASN.1 DER encoded ECDSA signature.
+func verifyECDSA(pubKey crypto.PublicKey, _ crypto.Hash, digest, sig []byte) error {
+	if !ecdsa.VerifyASN1(pubKey.(*ecdsa.PublicKey), digest, sig) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// verifySecp256k1 verifies an ASN.1 DER encoded ECDSA signature on the secp256k1 curve, which crypto/ecdsa does not
+// support.
+func verifySecp256k1(pubKey crypto.PublicKey, _ crypto.Hash, digest, sig []byte) error {
+	s, err := secp256k1ecdsa.ParseDERSignature(sig)
+	if err != nil {
+		return errors.Wrap(err, "invalid signature")
+	}
+	if !s.Verify(digest, pubKey.(*secp256k1.PublicKey)) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// verifyEdDSA verifies an Ed25519 signature, which signs data itself rather than its digest.
+func verifyEdDSA(pubKey crypto.PublicKey, _ crypto.Hash, data, sig []byte) error {
+	if !ed25519.Verify(pubKey.(ed25519.PublicKey), data, sig) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// verifyRSAPKCS1v15 verifies a RSASSA-PKCS1-v1_5 signature.
+func verifyRSAPKCS1v15(pubKey crypto.PublicKey, hash crypto.Hash, digest, sig []byte) error {
+	if err := rsa.VerifyPKCS1v15(pubKey.(*rsa.PublicKey), hash, digest, sig); err != nil {
+		return errors.Wrap(err, "invalid signature")
+	}
+	return nil
+}
+
+// verifyRSAPSS verifies a RSASSA-PSS signature whose salt is as long as the digest, see Section 2 of [RFC8230].
+func verifyRSAPSS(pubKey crypto.PublicKey, hash crypto.Hash, digest, sig []byte) error {
+	opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hash}
+	if err := rsa.VerifyPSS(pubKey.(*rsa.PublicKey), hash, digest, sig, opts); err != nil {
+		return errors.Wrap(err, "invalid signature")
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package cose
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/pkg/errors"
+	"math/big"
+)
+
+var (
+	oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidCurveSecp256k1 = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+)
+
+// jwaAlgorithms maps an algorithm onto its name in the JSON Web Algorithms registry, see [RFC7518] and [RFC8812].
+var jwaAlgorithms = map[Algorithm]string{
+	AlgES256:  "ES256",
+	AlgES384:  "ES384",
+	AlgES512:  "ES512",
+	AlgES256K: "ES256K",
+	AlgEdDSA:  "EdDSA",
+	AlgPS256:  "PS256",
+	AlgPS384:  "PS384",
+	AlgPS512:  "PS512",
+	AlgRS256:  "RS256",
+	AlgRS384:  "RS384",
+	AlgRS512:  "RS512",
+}
+
+// jwkCurves maps a curve onto its name in the JSON Web Key Elliptic Curve registry.
+var jwkCurves = map[Curve]string{
+	CurveP256:      "P-256",
+	CurveP384:      "P-384",
+	CurveP521:      "P-521",
+	CurveEd25519:   "Ed25519",
+	CurveSecp256k1: "secp256k1",
+}
+
+// MarshalPKIX converts key into the DER encoded SubjectPublicKeyInfo.
+func MarshalPKIX(key Key) ([]byte, error) {
+	pubKey, err := key.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	switch pub := pubKey.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		return x509.MarshalPKIXPublicKey(pub)
+	case *secp256k1.PublicKey:
+		// crypto/x509 does not know the secp256k1 curve.
+		params, err := asn1.Marshal(oidCurveSecp256k1)
+		if err != nil {
+			return nil, err
+		}
+		point := pub.SerializeUncompressed()
+		return asn1.Marshal(struct {
+			Algorithm pkix.AlgorithmIdentifier
+			PublicKey asn1.BitString
+		}{
+			Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA, Parameters: asn1.RawValue{FullBytes: params}},
+			PublicKey: asn1.BitString{Bytes: point, BitLength: 8 * len(point)},
+		})
+	}
+	return nil, errors.New(fmt.Sprintf("unsupported public key: %T", pubKey))
+}
+
+// MarshalPEM converts key into the PEM encoded SubjectPublicKeyInfo.
+func MarshalPEM(key Key) ([]byte, error) {
+	der, err := MarshalPKIX(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// JWK is a public JSON Web Key, see [RFC7517].
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set, see Section 5 of [RFC7517].
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewJWK converts key into a JWK for signature verification. kid is the base64url encoding of credentialID, which is
+// the same as the id of the PublicKeyCredential the key belongs to.
+func NewJWK(key Key, credentialID []byte) (*JWK, error) {
+	if err := Validate(key); err != nil {
+		return nil, err
+	}
+	jwk := &JWK{
+		Kid: base64.RawURLEncoding.EncodeToString(credentialID),
+		Use: "sig",
+		Alg: jwaAlgorithms[key.Algorithm()],
+	}
+	b64 := base64.RawURLEncoding.EncodeToString
+
+	switch k := key.(type) {
+	case *EC2Key:
+		jwk.Kty = "EC"
+		jwk.Crv = jwkCurves[k.Curve]
+		jwk.X = b64(k.X)
+		jwk.Y = b64(k.Y)
+	case *OKPKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = jwkCurves[k.Curve]
+		jwk.X = b64(k.X)
+	case *RSAKey:
+		jwk.Kty = "RSA"
+		jwk.N = b64(k.N)
+		// e is encoded in the minimum number of octets, see Section 6.3.1.2 of [RFC7518].
+		jwk.E = b64(new(big.Int).SetBytes(k.E).Bytes())
+	default:
+		return nil, errors.New(fmt.Sprintf("unsupported key: %T", key))
+	}
+	return jwk, nil
+}
@@ -0,0 +1,93 @@
+package cose
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+func TestMarshalPEM(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	edPub, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		name string
+		key  map[int]interface{}
+		want crypto.PublicKey
+	}{
+		{name: "ES256", key: ecMap(AlgES256, CurveP256, &ecKey.PublicKey), want: &ecKey.PublicKey},
+		{name: "RS256", key: rsaMap(AlgRS256, &rsaKey.PublicKey), want: &rsaKey.PublicKey},
+		{
+			name: "EdDSA",
+			key: map[int]interface{}{
+				labelKty: int(KeyTypeOKP),
+				labelAlg: int(AlgEdDSA),
+				labelCrv: int(CurveEd25519),
+				labelX:   []byte(edPub),
+			},
+			want: edPub,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := Parse(encode(t, tt.key))
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := MarshalPEM(key)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			block, _ := pem.Decode(b)
+			if block == nil || block.Type != "PUBLIC KEY" {
+				t.Fatalf("not a PEM encoded public key: %s", b)
+			}
+			pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !pub.(interface{ Equal(crypto.PublicKey) bool }).Equal(tt.want) {
+				t.Error("public key is not matched")
+			}
+		})
+	}
+}
+
+func TestNewJWK(t *testing.T) {
+	credentialID := []byte{0xde, 0xad, 0xbe, 0xef}
+	k1Key, _ := secp256k1.GeneratePrivateKey()
+	k1Pub := k1Key.PubKey().SerializeUncompressed()
+	key := &EC2Key{Alg: AlgES256K, Curve: CurveSecp256k1, X: k1Pub[1:33], Y: k1Pub[33:]}
+
+	jwk, err := NewJWK(key, credentialID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := JWK{
+		Kty: "EC",
+		Kid: "3q2-7w",
+		Use: "sig",
+		Alg: "ES256K",
+		Crv: "secp256k1",
+		X:   base64.RawURLEncoding.EncodeToString(k1Pub[1:33]),
+		Y:   base64.RawURLEncoding.EncodeToString(k1Pub[33:]),
+	}
+	if *jwk != want {
+		t.Errorf("got %+v, want %+v", *jwk, want)
+	}
+
+	if _, err := MarshalPKIX(key); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,272 @@
+package cose
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"fmt"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/miliya612/webauthn-demo/cbor"
+	"github.com/pkg/errors"
+	"github.com/ugorji/go/codec"
+	"math/big"
+)
+
+// KeyType is the COSE key type, see https://www.iana.org/assignments/cose/cose.xhtml#key-type
+type KeyType int
+
+const (
+	KeyTypeOKP KeyType = 1
+	KeyTypeEC2 KeyType = 2
+	KeyTypeRSA KeyType = 3
+)
+
+// Curve is the COSE elliptic curve, see https://www.iana.org/assignments/cose/cose.xhtml#elliptic-curves
+type Curve int
+
+const (
+	CurveP256    Curve = 1
+	CurveP384    Curve = 2
+	CurveP521    Curve = 3
+	CurveX25519  Curve = 4
+	CurveX448    Curve = 5
+	CurveEd25519 Curve = 6
+	CurveEd448   Curve = 7
+	// CurveSecp256k1 is the curve of ES256K, see [RFC8812].
+	CurveSecp256k1 Curve = 8
+)
+
+// Labels of the COSE_Key parameters, see Section 7.1 and 13 of [RFC8152].
+const (
+	labelKty    = 1
+	labelKid    = 2
+	labelAlg    = 3
+	labelKeyOps = 4
+	labelBaseIV = 5
+
+	labelCrv = -1
+	labelX   = -2
+	labelY   = -3
+	labelN   = -1
+	labelE   = -2
+)
+
+// Key is a credential public key in the COSE_Key format.
+type Key interface {
+	// KeyType returns kty of the key.
+	KeyType() KeyType
+	// Algorithm returns alg of the key, which is the only algorithm the key is used with.
+	Algorithm() Algorithm
+	// PublicKey converts the key into *ecdsa.PublicKey, *rsa.PublicKey or ed25519.PublicKey. Keys on the secp256k1
+	// curve are converted into *secp256k1.PublicKey of github.com/decred/dcrd/dcrec/secp256k1/v4.
+	PublicKey() (crypto.PublicKey, error)
+	// Verify verifies that sig is a signature over data made with the key by Algorithm.
+	Verify(data, sig []byte) error
+}
+
+// EC2Key is a public key on an elliptic curve, which is represented by its x and y coordinates.
+type EC2Key struct {
+	Alg   Algorithm
+	Curve Curve
+	X     []byte
+	Y     []byte
+}
+
+func (k *EC2Key) KeyType() KeyType     { return KeyTypeEC2 }
+func (k *EC2Key) Algorithm() Algorithm { return k.Alg }
+func (k *EC2Key) Verify(data, sig []byte) error {
+	return verify(k, data, sig)
+}
+
+func (k *EC2Key) PublicKey() (crypto.PublicKey, error) {
+	if k.Curve == CurveSecp256k1 {
+		if len(k.X) != 32 || len(k.Y) != 32 {
+			return nil, errors.New("invalid length of ec2 coordinates")
+		}
+		// secp256k1.ParsePubKey rejects points which are not on the curve.
+		pubKey, err := secp256k1.ParsePubKey(append(append([]byte{0x04}, k.X...), k.Y...))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid ec2 key")
+		}
+		return pubKey, nil
+	}
+
+	var curve elliptic.Curve
+	var ecdhCurve ecdh.Curve
+	switch k.Curve {
+	case CurveP256:
+		curve, ecdhCurve = elliptic.P256(), ecdh.P256()
+	case CurveP384:
+		curve, ecdhCurve = elliptic.P384(), ecdh.P384()
+	case CurveP521:
+		curve, ecdhCurve = elliptic.P521(), ecdh.P521()
+	default:
+		return nil, errors.New(fmt.Sprintf("unsupported ec2 curve: %d", k.Curve))
+	}
+
+	size := (curve.Params().BitSize + 7) / 8
+	if len(k.X) != size || len(k.Y) != size {
+		return nil, errors.New("invalid length of ec2 coordinates")
+	}
+	// crypto/ecdh rejects points which are not on the curve.
+	point := append(append([]byte{0x04}, k.X...), k.Y...)
+	if _, err := ecdhCurve.NewPublicKey(point); err != nil {
+		return nil, errors.Wrap(err, "invalid ec2 key")
+	}
+	return &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(k.X),
+		Y:     new(big.Int).SetBytes(k.Y),
+	}, nil
+}
+
+// RSAKey is a RSA public key.
+type RSAKey struct {
+	Alg Algorithm
+	N   []byte
+	E   []byte
+}
+
+func (k *RSAKey) KeyType() KeyType     { return KeyTypeRSA }
+func (k *RSAKey) Algorithm() Algorithm { return k.Alg }
+func (k *RSAKey) Verify(data, sig []byte) error {
+	return verify(k, data, sig)
+}
+
+func (k *RSAKey) PublicKey() (crypto.PublicKey, error) {
+	if len(k.N) == 0 || k.N[0] == 0 {
+		return nil, errors.New("invalid rsa modulus")
+	}
+	if len(k.E) == 0 || len(k.E) > 4 || k.E[0] == 0 {
+		return nil, errors.New("invalid rsa exponent")
+	}
+	e := new(big.Int).SetBytes(k.E)
+	if e.Int64() < 3 || e.Bit(0) == 0 {
+		return nil, errors.New("invalid rsa exponent")
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(k.N),
+		E: int(e.Int64()),
+	}, nil
+}
+
+// OKPKey is a public key on an octet key pair curve, i.e. Ed25519.
+type OKPKey struct {
+	Alg   Algorithm
+	Curve Curve
+	X     []byte
+}
+
+func (k *OKPKey) KeyType() KeyType     { return KeyTypeOKP }
+func (k *OKPKey) Algorithm() Algorithm { return k.Alg }
+func (k *OKPKey) Verify(data, sig []byte) error {
+	return verify(k, data, sig)
+}
+
+func (k *OKPKey) PublicKey() (crypto.PublicKey, error) {
+	if k.Curve != CurveEd25519 {
+		return nil, errors.New(fmt.Sprintf("unsupported okp curve: %d", k.Curve))
+	}
+	if len(k.X) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid length of okp public key")
+	}
+	return ed25519.PublicKey(append([]byte{}, k.X...)), nil
+}
+
+// Parse decodes a COSE_Key encoded credential public key and validates it.
+func Parse(buf []byte) (Key, error) {
+	n, err := cbor.ItemLength(buf)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decode COSE key")
+	}
+	m := make(map[int]interface{})
+	if err := codec.NewDecoderBytes(buf[:n], cbor.NewHandle()).Decode(&m); err != nil {
+		return nil, errors.Wrap(err, "unable to decode COSE key")
+	}
+	return FromMap(m)
+}
+
+// FromMap builds a Key from a decoded COSE_Key and validates it.
+func FromMap(m map[int]interface{}) (Key, error) {
+	// The COSE_Key-encoded credential public key MUST contain the "alg" parameter and MUST NOT contain any other
+	// OPTIONAL parameters. The "alg" parameter MUST contain a COSEAlgorithmIdentifier value. The encoded credential
+	// public key MUST also contain any additional REQUIRED parameters stipulated by the relevant key type
+	// specification, i.e., REQUIRED for the key type "kty" and algorithm "alg" (see Section 8 of [RFC8152]).
+	kty, ok := toInt(m[labelKty])
+	if !ok {
+		return nil, errors.New("kty is missing in COSE key")
+	}
+	alg, ok := toInt(m[labelAlg])
+	if !ok {
+		return nil, errors.New("alg is missing in COSE key")
+	}
+	for _, label := range []int{labelKid, labelKeyOps, labelBaseIV} {
+		if _, ok := m[label]; ok {
+			return nil, errors.New(fmt.Sprintf("COSE key must not contain the optional parameter %d", label))
+		}
+	}
+
+	var key Key
+	switch KeyType(kty) {
+	case KeyTypeEC2:
+		crv, _ := toInt(m[labelCrv])
+		x, _ := m[labelX].([]byte)
+		y, _ := m[labelY].([]byte)
+		key = &EC2Key{Alg: Algorithm(alg), Curve: Curve(crv), X: x, Y: y}
+	case KeyTypeRSA:
+		n, _ := m[labelN].([]byte)
+		e, _ := m[labelE].([]byte)
+		key = &RSAKey{Alg: Algorithm(alg), N: n, E: e}
+	case KeyTypeOKP:
+		crv, _ := toInt(m[labelCrv])
+		x, _ := m[labelX].([]byte)
+		key = &OKPKey{Alg: Algorithm(alg), Curve: Curve(crv), X: x}
+	default:
+		return nil, errors.New(fmt.Sprintf("unsupported key type: %d", kty))
+	}
+
+	if err := Validate(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Validate checks that the algorithm of key is consistent with its key type and curve, and that key is convertible
+// into a crypto.PublicKey.
+func Validate(key Key) error {
+	params, ok := algorithms[key.Algorithm()]
+	if !ok {
+		return errors.New(fmt.Sprintf("unsupported algorithm: %d", key.Algorithm()))
+	}
+	if params.keyType != key.KeyType() {
+		return errors.New(fmt.Sprintf("algorithm %d is not matched with key type %d", key.Algorithm(), key.KeyType()))
+	}
+	var crv Curve
+	switch k := key.(type) {
+	case *EC2Key:
+		crv = k.Curve
+	case *OKPKey:
+		crv = k.Curve
+	}
+	if params.curve != crv {
+		return errors.New(fmt.Sprintf("algorithm %d is not matched with curve %d", key.Algorithm(), crv))
+	}
+	_, err := key.PublicKey()
+	return err
+}
+
+// toInt converts an integer decoded from CBOR into int.
+func toInt(v interface{}) (int, bool) {
+	switch i := v.(type) {
+	case int64:
+		return int(i), true
+	case uint64:
+		return int(i), true
+	case int:
+		return i, true
+	}
+	return 0, false
+}
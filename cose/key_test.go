@@ -0,0 +1,197 @@
+package cose
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"math/big"
+	"runtime"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secp256k1ecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/ugorji/go/codec"
+)
+
+func encode(t *testing.T, m map[int]interface{}) []byte {
+	t.Helper()
+	var b []byte
+	if err := codec.NewEncoderBytes(&b, &codec.CborHandle{}).Encode(m); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func ecMap(alg Algorithm, crv Curve, pub *ecdsa.PublicKey) map[int]interface{} {
+	size := (pub.Curve.Params().BitSize + 7) / 8
+	return map[int]interface{}{
+		labelKty: int(KeyTypeEC2),
+		labelAlg: int(alg),
+		labelCrv: int(crv),
+		labelX:   pub.X.FillBytes(make([]byte, size)),
+		labelY:   pub.Y.FillBytes(make([]byte, size)),
+	}
+}
+
+func rsaMap(alg Algorithm, pub *rsa.PublicKey) map[int]interface{} {
+	return map[int]interface{}{
+		labelKty: int(KeyTypeRSA),
+		labelAlg: int(alg),
+		labelN:   pub.N.Bytes(),
+		labelE:   big.NewInt(int64(pub.E)).Bytes(),
+	}
+}
+
+func TestParseAndVerify(t *testing.T) {
+	data := []byte("authenticatorData || clientDataHash")
+	digest := sha256.Sum256(data)
+	digest384 := sha512.Sum384(data)
+	digest512 := sha512.Sum512(data)
+
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ecSig, _ := ecdsa.SignASN1(rand.Reader, ecKey, digest[:])
+	ec384Key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	ec384Sig, _ := ecdsa.SignASN1(rand.Reader, ec384Key, digest384[:])
+	ec521Key, _ := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	ec521Sig, _ := ecdsa.SignASN1(rand.Reader, ec521Key, digest512[:])
+
+	k1Key, _ := secp256k1.GeneratePrivateKey()
+	k1Pub := k1Key.PubKey().SerializeUncompressed()
+	k1Sig := secp256k1ecdsa.Sign(k1Key, digest[:]).Serialize()
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rsaSig, _ := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	rs512Sig, _ := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA512, digest512[:])
+	pssOpts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+	ps256Sig, _ := rsa.SignPSS(rand.Reader, rsaKey, crypto.SHA256, digest[:], pssOpts)
+	ps384Sig, _ := rsa.SignPSS(rand.Reader, rsaKey, crypto.SHA384, digest384[:], pssOpts)
+
+	edPub, edPriv, _ := ed25519.GenerateKey(rand.Reader)
+	edSig := ed25519.Sign(edPriv, data)
+
+	tests := []struct {
+		name string
+		key  map[int]interface{}
+		sig  []byte
+		want crypto.PublicKey
+	}{
+		{name: "ES256", key: ecMap(AlgES256, CurveP256, &ecKey.PublicKey), sig: ecSig, want: &ecKey.PublicKey},
+		{name: "ES384", key: ecMap(AlgES384, CurveP384, &ec384Key.PublicKey), sig: ec384Sig, want: &ec384Key.PublicKey},
+		{name: "ES512", key: ecMap(AlgES512, CurveP521, &ec521Key.PublicKey), sig: ec521Sig, want: &ec521Key.PublicKey},
+		{
+			name: "ES256K",
+			key: map[int]interface{}{
+				labelKty: int(KeyTypeEC2),
+				labelAlg: int(AlgES256K),
+				labelCrv: int(CurveSecp256k1),
+				labelX:   k1Pub[1:33],
+				labelY:   k1Pub[33:],
+			},
+			sig:  k1Sig,
+			want: k1Key.PubKey(),
+		},
+		{name: "RS256", key: rsaMap(AlgRS256, &rsaKey.PublicKey), sig: rsaSig, want: &rsaKey.PublicKey},
+		{name: "RS512", key: rsaMap(AlgRS512, &rsaKey.PublicKey), sig: rs512Sig, want: &rsaKey.PublicKey},
+		{name: "PS256", key: rsaMap(AlgPS256, &rsaKey.PublicKey), sig: ps256Sig, want: &rsaKey.PublicKey},
+		{name: "PS384", key: rsaMap(AlgPS384, &rsaKey.PublicKey), sig: ps384Sig, want: &rsaKey.PublicKey},
+		{
+			name: "EdDSA",
+			key: map[int]interface{}{
+				labelKty: int(KeyTypeOKP),
+				labelAlg: int(AlgEdDSA),
+				labelCrv: int(CurveEd25519),
+				labelX:   []byte(edPub),
+			},
+			sig:  edSig,
+			want: edPub,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := Parse(encode(t, tt.key))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			pub, err := key.PublicKey()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if k1, ok := pub.(*secp256k1.PublicKey); ok {
+				if !k1.IsEqual(tt.want.(*secp256k1.PublicKey)) {
+					t.Error("public key is not matched")
+				}
+			} else if !pub.(interface{ Equal(crypto.PublicKey) bool }).Equal(tt.want) {
+				t.Error("public key is not matched")
+			}
+			if err := key.Verify(data, tt.sig); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if err := key.Verify([]byte("tampered"), tt.sig); err == nil {
+				t.Error("expected an error for tampered data, but got nil")
+			}
+		})
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	tests := []struct {
+		name   string
+		modify func(m map[int]interface{})
+	}{
+		{name: "alg is missing", modify: func(m map[int]interface{}) { delete(m, labelAlg) }},
+		{name: "kid is present", modify: func(m map[int]interface{}) { m[labelKid] = []byte("kid") }},
+		{name: "alg is not matched with kty", modify: func(m map[int]interface{}) { m[labelAlg] = int(AlgRS256) }},
+		{name: "crv is not matched with alg", modify: func(m map[int]interface{}) { m[labelCrv] = int(CurveP384) }},
+		{name: "x is short", modify: func(m map[int]interface{}) { m[labelX] = m[labelX].([]byte)[1:] }},
+		{name: "point is not on the curve", modify: func(m map[int]interface{}) { m[labelY] = make([]byte, 32) }},
+		{name: "unknown kty", modify: func(m map[int]interface{}) { m[labelKty] = 4 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := ecMap(AlgES256, CurveP256, &ecKey.PublicKey)
+			tt.modify(m)
+			if _, err := Parse(encode(t, m)); err == nil {
+				t.Error("expected an error, but got nil")
+			}
+		})
+	}
+}
+
+func TestParseHugeCollections(t *testing.T) {
+	// Maps which declare 0x08080808 pairs each, nested as values of -17 and then 0.
+	nested := append([]byte{0xa1, 0x30}, bytes.Repeat([]byte{0xba, 0x08, 0x08, 0x08, 0x08, 0x00}, 64)...)
+
+	tests := []struct {
+		name string
+		buf  []byte
+	}{
+		{name: "huge array", buf: []byte{0xa1, 0x30, 0x9b, 0x08, 0x08, 0x08, 0x08, 0x08, 0x08, 0x08, 0x08}},
+		{name: "nested huge maps", buf: nested},
+		{name: "huge chunk of an indefinite-length string", buf: []byte{0xa1, 0x30, 0x7f, 0x7a, 0xfe, 0x20, 0xad, 0x74}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			_, err := Parse(tt.buf)
+			runtime.ReadMemStats(&after)
+			if err == nil {
+				t.Error("expected an error, but got nil")
+			}
+			if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 64*1024 {
+				t.Errorf("got %d bytes allocated for %d bytes, want at most 64KiB", alloc, len(tt.buf))
+			}
+		})
+	}
+}
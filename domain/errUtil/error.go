@@ -5,3 +5,28 @@ type ErrTodoNotFound struct{}
 func (e ErrTodoNotFound) Error() string {
 	return "todo is not found"
 }
+
+type ErrUserNotFound struct{}
+
+func (e ErrUserNotFound) Error() string {
+	return "user not found"
+}
+
+type ErrCredentialNotFound struct{}
+
+func (e ErrCredentialNotFound) Error() string {
+	return "credential not found"
+}
+
+type ErrSessionNotFound struct{}
+
+func (e ErrSessionNotFound) Error() string {
+	return "session not found"
+}
+
+// ErrUserConflict is returned when a user is created with the user handle of a registered user.
+type ErrUserConflict struct{}
+
+func (e ErrUserConflict) Error() string {
+	return "user already exists"
+}
@@ -1,8 +1,13 @@
 package model
 
 type Credential struct {
+	// TenantID is the tenant the credential is registered with.
+	TenantID     string
 	CredentialID []byte
 	UserID       []byte
 	PublicKey    []byte
 	SignCount    uint32
+	// AttestationType is the trust level of the attestation the credential was registered with, which is one of
+	// "Basic", "AttCA", "AnonCA", "Self" and "None".
+	AttestationType string
 }
@@ -3,8 +3,13 @@ package model
 import "time"
 
 type Session struct {
+	// TenantID is the tenant the ceremony is run for.
+	TenantID     string
 	ID           string
 	UserID       []byte
 	Challenge    []byte
 	LastAccessed time.Time
+	// Authenticated is whether the user has been authenticated in the session, which is required to register another
+	// credential with the account.
+	Authenticated bool
 }
@@ -1,6 +1,8 @@
 package model
 
 type User struct {
+	// TenantID is the tenant the user account belongs to.
+	TenantID    string
 	ID          []byte
 	Name        string
 	DisplayName string
@@ -2,11 +2,15 @@ package repo
 
 import "github.com/miliya612/webauthn-demo/domain/model"
 
+// CredentialRepo stores credentials partitioned by tenant. A credential is looked up only within the tenant it is
+// registered with.
 type CredentialRepo interface {
-	GetByCredentialID(id []byte) (*model.Credential, error)
+	GetByCredentialID(tenantID string, id []byte) (*model.Credential, error)
+	GetByUserID(tenantID string, userId []byte) ([]model.Credential, error)
 	Create(credential model.Credential) (*model.Credential, error)
 	Update(credential model.Credential) (*model.Credential, error)
-	Delete(id []byte) ([]byte, error)
-	// GetCount(id []byte) (int, error)
-	// UpdateCount(id []byte, count id) (*model.Credential, error)
+	Delete(tenantID string, id []byte) ([]byte, error)
+	// UpdateSignCount sets the signature counter of the credential to signCount only if signCount is greater than the
+	// stored one, which is compared and set atomically. It returns false if the counter has not been updated.
+	UpdateSignCount(tenantID string, id []byte, signCount uint32) (bool, error)
 }
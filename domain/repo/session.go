@@ -2,8 +2,9 @@ package repo
 
 import "github.com/miliya612/webauthn-demo/domain/model"
 
+// SessionRepo stores sessions partitioned by tenant.
 type SessionRepo interface {
-	GetByID(id string) (*model.Session, error)
+	GetByID(tenantID string, id string) (*model.Session, error)
 	Create(session model.Session) (*model.Session, error)
-	Delete(id string) (int, error)
+	Delete(tenantID string, id string) (int, error)
 }
@@ -2,8 +2,9 @@ package repo
 
 import "github.com/miliya612/webauthn-demo/domain/model"
 
+// UserRepo stores user accounts partitioned by tenant.
 type UserRepo interface {
-	GetByID(id []byte) (*model.User, error)
+	GetByID(tenantID string, id []byte) (*model.User, error)
 	Create(user model.User) (*model.User, error)
 	Update(user model.User) (*model.User, error)
 }
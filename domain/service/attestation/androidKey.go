@@ -0,0 +1,236 @@
+package attestation
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/asn1"
+	"github.com/miliya612/webauthn-demo/webauthnif"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	RegisterAttVerifier(webauthnif.AttestationStatementFormatAndroidKey, NewAndroidKeyVerifier(DefaultAndroidKeyPolicy))
+}
+
+// idAndroidKeyAttestation is the OID of the Android Key Attestation extension.
+var idAndroidKeyAttestation = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 1, 17}
+
+// Keymaster constants and AuthorizationList tags, see https://source.android.com/security/keystore/tags
+const (
+	kmOriginGenerated = 0
+	kmPurposeSign     = 2
+
+	kmTagPurpose         = 1
+	kmTagAllApplications = 600
+	kmTagOrigin          = 702
+)
+
+// AndroidKeyPolicy is the Relying Party policy on the authorization lists of an Android Key Attestation.
+type AndroidKeyPolicy struct {
+	// AllowAllApplications accepts keys whose allApplications field is present, i.e. keys which are not scoped to the
+	// RP ID.
+	AllowAllApplications bool `yaml:"allowAllApplications" json:"allowAllApplications"`
+	// TEEOnly uses only the teeEnforced authorization list to check origin and purpose. Otherwise the union of
+	// teeEnforced and softwareEnforced is used.
+	TEEOnly bool `yaml:"teeOnly" json:"teeOnly"`
+	// RequireOriginGenerated requires the origin field to be KM_ORIGIN_GENERATED.
+	RequireOriginGenerated bool `yaml:"requireOriginGenerated" json:"requireOriginGenerated"`
+	// RequirePurposeSign requires the purpose field to contain KM_PURPOSE_SIGN.
+	RequirePurposeSign bool `yaml:"requirePurposeSign" json:"requirePurposeSign"`
+}
+
+// DefaultAndroidKeyPolicy follows the verification procedure of WebAuthn. It is the policy of the verifier registered
+// in AttVerifiers, which is replaced by the policy of the Relying Party with Trust.Verifiers.
+var DefaultAndroidKeyPolicy = AndroidKeyPolicy{
+	AllowAllApplications:   false,
+	TEEOnly:                false,
+	RequireOriginGenerated: true,
+	RequirePurposeSign:     true,
+}
+
+// androidKeyDescription is the KeyDescription sequence of the Android Key Attestation extension.
+type androidKeyDescription struct {
+	AttestationVersion       int
+	AttestationSecurityLevel asn1.Enumerated
+	KeymasterVersion         int
+	KeymasterSecurityLevel   asn1.Enumerated
+	AttestationChallenge     []byte
+	UniqueID                 []byte
+	SoftwareEnforced         asn1.RawValue
+	TeeEnforced              asn1.RawValue
+}
+
+// androidAuthorizationList holds the fields of an AuthorizationList which are used by the verification procedure.
+type androidAuthorizationList struct {
+	Purpose         []int
+	AllApplications bool
+	Origin          *int
+}
+
+// NewAndroidKeyVerifier returns the verification procedure of the android-key attestation statement format which
+// enforces policy.
+func NewAndroidKeyVerifier(policy AndroidKeyPolicy) AttVerifyFunc {
+	return func(attObj webauthnif.DecodedAttestationObject, clientDataHash [32]byte) (*Result, error) {
+		return verifyAndroidKey(attObj, clientDataHash, policy)
+	}
+}
+
+// 8.4
+// verifyAndroidKey verifies an attestation statement generated by authenticators on the Android "N" or later.
+// See https://www.w3.org/TR/webauthn/#android-key-attestation
+func verifyAndroidKey(
+	attObj webauthnif.DecodedAttestationObject, clientDataHash [32]byte, policy AndroidKeyPolicy) (*Result, error) {
+	// Verify that attStmt is valid CBOR conforming to the syntax defined above and perform CBOR decoding on it to
+	// extract the contained fields.
+	stmt, err := attStmtMap(attObj)
+	if err != nil {
+		return nil, err
+	}
+	alg, err := algFromAttStmt(stmt)
+	if err != nil {
+		return nil, err
+	}
+	sig, ok := stmt["sig"].([]byte)
+	if !ok {
+		return nil, errors.New("sig is missing in attStmt")
+	}
+	x5c, err := x5cFromAttStmt(stmt)
+	if err != nil {
+		return nil, err
+	}
+	if x5c == nil {
+		return nil, errors.New("x5c is missing in attStmt")
+	}
+	credCert := x5c[0]
+
+	// Verify that sig is a valid signature over the concatenation of authenticatorData and clientDataHash using the
+	// public key in the first certificate in x5c with the algorithm specified in alg.
+	sigAlg, err := x509SignatureAlgorithm(alg)
+	if err != nil {
+		return nil, err
+	}
+	if err := credCert.CheckSignature(sigAlg, signedData(attObj, clientDataHash), sig); err != nil {
+		return nil, errors.Wrap(err, "invalid attestation signature")
+	}
+
+	// Verify that the public key in the first certificate in x5c matches the credentialPublicKey in the
+	// attestedCredentialData in authenticatorData.
+	credPubKey, err := credentialPublicKey(attObj)
+	if err != nil {
+		return nil, err
+	}
+	certPubKey, ok := credCert.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !certPubKey.Equal(credPubKey) {
+		return nil, errors.New("credential public key is not matched with the attestation certificate")
+	}
+
+	// Verify that the attestationChallenge field in the attestation certificate extension data is identical to
+	// clientDataHash.
+	var keyDescription *androidKeyDescription
+	for _, ext := range credCert.Extensions {
+		if !ext.Id.Equal(idAndroidKeyAttestation) {
+			continue
+		}
+		keyDescription = &androidKeyDescription{}
+		if _, err := asn1.Unmarshal(ext.Value, keyDescription); err != nil {
+			return nil, errors.Wrap(err, "unable to parse android key attestation extension")
+		}
+	}
+	if keyDescription == nil {
+		return nil, errors.New("android key attestation extension is missing")
+	}
+	if !bytes.Equal(keyDescription.AttestationChallenge, clientDataHash[:]) {
+		return nil, errors.New("attestationChallenge is not matched with clientDataHash")
+	}
+
+	// Verify the following using the appropriate authorization list from the attestation certificate extension
+	// data:
+	softwareEnforced, err := parseAndroidAuthorizationList(keyDescription.SoftwareEnforced)
+	if err != nil {
+		return nil, err
+	}
+	teeEnforced, err := parseAndroidAuthorizationList(keyDescription.TeeEnforced)
+	if err != nil {
+		return nil, err
+	}
+
+	//   - The AuthorizationList.allApplications field is not present on either authorization list
+	//     (softwareEnforced nor teeEnforced), since PublicKeyCredential MUST be scoped to the RP ID.
+	if !policy.AllowAllApplications && (softwareEnforced.AllApplications || teeEnforced.AllApplications) {
+		return nil, errors.New("allApplications must not be present")
+	}
+
+	//   - For the following, use only the teeEnforced authorization list if the RP wants to accept only keys from a
+	//     trusted execution environment, otherwise use the union of teeEnforced and softwareEnforced.
+	lists := []*androidAuthorizationList{teeEnforced}
+	if !policy.TEEOnly {
+		lists = append(lists, softwareEnforced)
+	}
+	//     - The value in the AuthorizationList.origin field is equal to KM_ORIGIN_GENERATED.
+	if policy.RequireOriginGenerated {
+		generated := false
+		for _, l := range lists {
+			if l.Origin != nil && *l.Origin == kmOriginGenerated {
+				generated = true
+			}
+		}
+		if !generated {
+			return nil, errors.New("origin must be KM_ORIGIN_GENERATED")
+		}
+	}
+	//     - The value in the AuthorizationList.purpose field is equal to KM_PURPOSE_SIGN.
+	if policy.RequirePurposeSign {
+		sign := false
+		for _, l := range lists {
+			for _, p := range l.Purpose {
+				if p == kmPurposeSign {
+					sign = true
+				}
+			}
+		}
+		if !sign {
+			return nil, errors.New("purpose must be KM_PURPOSE_SIGN")
+		}
+	}
+
+	// If successful, return attestation type Basic with the attestation trust path set to x5c.
+	return &Result{
+		Type:      AttestationTypeBasic,
+		TrustPath: x5c,
+	}, nil
+}
+
+// parseAndroidAuthorizationList parses the fields of an AuthorizationList. Every field of an AuthorizationList is
+// OPTIONAL and EXPLICIT tagged, so it is read element by element and unknown tags are skipped.
+func parseAndroidAuthorizationList(raw asn1.RawValue) (*androidAuthorizationList, error) {
+	l := &androidAuthorizationList{}
+	rest := raw.Bytes
+	for len(rest) > 0 {
+		var field asn1.RawValue
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &field); err != nil {
+			return nil, errors.Wrap(err, "unable to parse authorization list")
+		}
+		if field.Class != asn1.ClassContextSpecific {
+			continue
+		}
+
+		switch field.Tag {
+		case kmTagPurpose:
+			var purpose []int
+			if _, err := asn1.UnmarshalWithParams(field.Bytes, &purpose, "set"); err != nil {
+				return nil, errors.Wrap(err, "unable to parse purpose")
+			}
+			l.Purpose = purpose
+		case kmTagAllApplications:
+			l.AllApplications = true
+		case kmTagOrigin:
+			var origin int
+			if _, err := asn1.Unmarshal(field.Bytes, &origin); err != nil {
+				return nil, errors.Wrap(err, "unable to parse origin")
+			}
+			l.Origin = &origin
+		}
+	}
+	return l, nil
+}
@@ -0,0 +1,204 @@
+package attestation
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"testing"
+
+	"github.com/miliya612/webauthn-demo/webauthnif"
+)
+
+// authorizationList is the content of an AuthorizationList of the fixtures.
+type authorizationList struct {
+	purpose         []int
+	allApplications bool
+	origin          *int
+}
+
+// marshal encodes l as an AuthorizationList, whose fields are EXPLICIT tagged.
+func (l authorizationList) marshal(t *testing.T) asn1.RawValue {
+	t.Helper()
+	var fields []byte
+	field := func(tag int, v []byte, err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: tag, IsCompound: true, Bytes: v})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields = append(fields, b...)
+	}
+	if l.purpose != nil {
+		v, err := asn1.MarshalWithParams(l.purpose, "set")
+		field(kmTagPurpose, v, err)
+	}
+	if l.allApplications {
+		field(kmTagAllApplications, asn1.NullBytes, nil)
+	}
+	if l.origin != nil {
+		v, err := asn1.Marshal(*l.origin)
+		field(kmTagOrigin, v, err)
+	}
+	b, err := asn1.Marshal(
+		asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSequence, IsCompound: true, Bytes: fields})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return asn1.RawValue{FullBytes: b}
+}
+
+// androidKeyFixture is an attestation object in the android-key format, whose credential certificate is issued by a
+// test CA.
+type androidKeyFixture struct {
+	ca               *testCA
+	credKey          *ecdsa.PrivateKey
+	rawAuthData      []byte
+	clientDataHash   [32]byte
+	challenge        []byte
+	softwareEnforced authorizationList
+	teeEnforced      authorizationList
+}
+
+func newAndroidKeyFixture(t *testing.T) *androidKeyFixture {
+	t.Helper()
+	f := &androidKeyFixture{
+		ca:             newTestCA(t),
+		clientDataHash: sha256.Sum256([]byte(`{"type":"webauthn.create"}`)),
+	}
+	var err error
+	if f.credKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	f.rawAuthData = authDataWithKey(t, &f.credKey.PublicKey)
+	f.challenge = f.clientDataHash[:]
+	generated := kmOriginGenerated
+	f.teeEnforced = authorizationList{purpose: []int{kmPurposeSign}, origin: &generated}
+	return f
+}
+
+func (f *androidKeyFixture) attestationObject(t *testing.T) webauthnif.DecodedAttestationObject {
+	t.Helper()
+	ext, err := asn1.Marshal(androidKeyDescription{
+		AttestationVersion:       3,
+		AttestationSecurityLevel: 1,
+		KeymasterVersion:         4,
+		KeymasterSecurityLevel:   1,
+		AttestationChallenge:     f.challenge,
+		UniqueID:                 []byte{},
+		SoftwareEnforced:         f.softwareEnforced.marshal(t),
+		TeeEnforced:              f.teeEnforced.marshal(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := f.ca.issue(t, &x509.Certificate{
+		Subject:         pkix.Name{CommonName: "Android Keystore Key"},
+		ExtraExtensions: []pkix.Extension{{Id: idAndroidKeyAttestation, Value: ext}},
+	}, &f.credKey.PublicKey)
+
+	digest := sha256.Sum256(append(append([]byte{}, f.rawAuthData...), f.clientDataHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, f.credKey, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decodeAttestationObject(t, map[string]interface{}{
+		"fmt":      "android-key",
+		"authData": f.rawAuthData,
+		"attStmt": map[string]interface{}{
+			"alg": int64(webauthnif.COSEAlgorithmIdentifierES256),
+			"sig": sig,
+			"x5c": []interface{}{cert, f.ca.cert.Raw},
+		},
+	})
+}
+
+func TestVerifyAndroidKey(t *testing.T) {
+	imported := 2
+
+	tests := []struct {
+		name    string
+		policy  func(p *AndroidKeyPolicy)
+		modify  func(f *androidKeyFixture)
+		wantErr bool
+	}{
+		{name: "default policy"},
+		{
+			name:    "attestationChallenge is not matched with clientDataHash",
+			modify:  func(f *androidKeyFixture) { f.challenge = make([]byte, 32) },
+			wantErr: true,
+		},
+		{
+			name:    "allApplications is present",
+			modify:  func(f *androidKeyFixture) { f.softwareEnforced.allApplications = true },
+			wantErr: true,
+		},
+		{
+			name:   "allApplications is present and allowed",
+			policy: func(p *AndroidKeyPolicy) { p.AllowAllApplications = true },
+			modify: func(f *androidKeyFixture) { f.teeEnforced.allApplications = true },
+		},
+		{
+			name:    "origin is not KM_ORIGIN_GENERATED",
+			modify:  func(f *androidKeyFixture) { f.teeEnforced.origin = &imported },
+			wantErr: true,
+		},
+		{
+			name:   "origin is not required",
+			policy: func(p *AndroidKeyPolicy) { p.RequireOriginGenerated = false },
+			modify: func(f *androidKeyFixture) { f.teeEnforced.origin = &imported },
+		},
+		{
+			name:    "purpose is not KM_PURPOSE_SIGN",
+			modify:  func(f *androidKeyFixture) { f.teeEnforced.purpose = []int{0, 1} },
+			wantErr: true,
+		},
+		{
+			name: "origin and purpose are software enforced",
+			modify: func(f *androidKeyFixture) {
+				f.softwareEnforced, f.teeEnforced = f.teeEnforced, authorizationList{}
+			},
+		},
+		{
+			name:   "origin and purpose are software enforced, but TEE is required",
+			policy: func(p *AndroidKeyPolicy) { p.TEEOnly = true },
+			modify: func(f *androidKeyFixture) {
+				f.softwareEnforced, f.teeEnforced = f.teeEnforced, authorizationList{}
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAndroidKeyFixture(t)
+			if tt.modify != nil {
+				tt.modify(f)
+			}
+			policy := DefaultAndroidKeyPolicy
+			if tt.policy != nil {
+				tt.policy(&policy)
+			}
+
+			result, err := NewAndroidKeyVerifier(policy)(f.attestationObject(t), f.clientDataHash)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected an error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Type != AttestationTypeBasic || len(result.TrustPath) != 2 {
+				t.Errorf("got %v with trust path of length %d, want %v with 2",
+					result.Type, len(result.TrustPath), AttestationTypeBasic)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package attestation
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/miliya612/webauthn-demo/domain/service/jws"
+	"github.com/miliya612/webauthn-demo/webauthnif"
+	"github.com/pkg/errors"
+	"time"
+)
+
+func init() {
+	RegisterAttVerifier(
+		webauthnif.AttestationStatementFormatAndroidSafetyNet,
+		NewAndroidSafetyNetVerifier(DefaultAndroidSafetyNetConfig),
+	)
+}
+
+// safetyNetHostname is the hostname which the leaf certificate of a SafetyNet response MUST be issued to.
+const safetyNetHostname = "attest.android.com"
+
+// AndroidSafetyNetConfig is the Relying Party configuration to verify SafetyNet responses.
+type AndroidSafetyNetConfig struct {
+	// Roots is the set of root certificates the SafetyNet response has to chain up to. The system roots are used if
+	// Roots is nil.
+	Roots *x509.CertPool
+	// MaxTimestampSkew is the maximum difference between timestampMs of the SafetyNet response and the current time.
+	MaxTimestampSkew time.Duration
+	// Now returns the current time. It is used to check timestampMs and the validity of the certificates.
+	Now func() time.Time
+}
+
+// DefaultAndroidSafetyNetConfig uses the system roots and the wall clock.
+var DefaultAndroidSafetyNetConfig = AndroidSafetyNetConfig{
+	Roots:            nil,
+	MaxTimestampSkew: time.Minute,
+	Now:              time.Now,
+}
+
+// safetyNetPayload is the payload of a SafetyNet response.
+// See https://developer.android.com/training/safetynet/attestation#use-response-server
+type safetyNetPayload struct {
+	Nonce           string `json:"nonce"`
+	TimestampMs     int64  `json:"timestampMs"`
+	ApkPackageName  string `json:"apkPackageName"`
+	CtsProfileMatch bool   `json:"ctsProfileMatch"`
+	BasicIntegrity  bool   `json:"basicIntegrity"`
+}
+
+// NewAndroidSafetyNetVerifier returns the verification procedure of the android-safetynet attestation statement
+// format with config.
+func NewAndroidSafetyNetVerifier(config AndroidSafetyNetConfig) AttVerifyFunc {
+	return func(attObj webauthnif.DecodedAttestationObject, clientDataHash [32]byte) (*Result, error) {
+		return verifyAndroidSafetyNet(attObj, clientDataHash, config)
+	}
+}
+
+// 8.5
+// verifyAndroidSafetyNet verifies an attestation statement generated by platform authenticators on Android which use
+// the SafetyNet API.
+// See https://www.w3.org/TR/webauthn/#android-safetynet-attestation
+func verifyAndroidSafetyNet(
+	attObj webauthnif.DecodedAttestationObject, clientDataHash [32]byte, config AndroidSafetyNetConfig,
+) (*Result, error) {
+	// Verify that attStmt is valid CBOR conforming to the syntax defined above and perform CBOR decoding on it to
+	// extract the contained fields.
+	stmt, err := attStmtMap(attObj)
+	if err != nil {
+		return nil, err
+	}
+	if ver, _ := stmt["ver"].(string); ver == "" {
+		return nil, errors.New("ver is missing in attStmt")
+	}
+	response, ok := stmt["response"].([]byte)
+	if !ok {
+		return nil, errors.New("response is missing in attStmt")
+	}
+
+	// Verify that response is a valid SafetyNet response of version ver.
+	token, err := jws.Parse(string(response))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid response")
+	}
+	var payload safetyNetPayload
+	if err := json.Unmarshal(token.Payload, &payload); err != nil {
+		return nil, errors.Wrap(err, "invalid response payload")
+	}
+
+	// Verify that the nonce in the response is identical to the Base64 encoding of the SHA-256 hash of the
+	// concatenation of authenticatorData and clientDataHash.
+	nonce := sha256.Sum256(signedData(attObj, clientDataHash))
+	if payload.Nonce != base64.StdEncoding.EncodeToString(nonce[:]) {
+		return nil, errors.New("nonce is not matched with authenticatorData and clientDataHash")
+	}
+
+	// Verify that the SafetyNet response actually came from the SafetyNet service by following the steps in the
+	// SafetyNet online documentation.
+	now := config.Now()
+	err = token.VerifyChain(x509.VerifyOptions{
+		DNSName:     safetyNetHostname,
+		Roots:       config.Roots,
+		CurrentTime: now,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid response")
+	}
+
+	// Verify that the ctsProfileMatch attribute in the payload of response is true.
+	if !payload.CtsProfileMatch {
+		return nil, errors.New("ctsProfileMatch must be true")
+	}
+
+	// The timestampMs is checked against the clock, so that a stale response can not be replayed.
+	timestamp := time.Unix(0, payload.TimestampMs*int64(time.Millisecond))
+	if skew := now.Sub(timestamp); skew > config.MaxTimestampSkew || -skew > config.MaxTimestampSkew {
+		return nil, errors.New(fmt.Sprintf("timestampMs is out of range: %v", timestamp))
+	}
+
+	// If successful, return attestation type Basic with the attestation trust path set to the above attestation
+	// certificate.
+	// The trust path has been verified against the roots of the SafetyNet service in config.
+	return &Result{
+		Type:              AttestationTypeBasic,
+		TrustPath:         token.Certificates,
+		TrustPathVerified: true,
+	}, nil
+}
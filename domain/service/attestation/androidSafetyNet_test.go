@@ -0,0 +1,193 @@
+package attestation
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/miliya612/webauthn-demo/domain/service/jws"
+	"github.com/miliya612/webauthn-demo/webauthnif"
+)
+
+// safetyNetFixture is a SafetyNet response signed by a certificate for attest.android.com issued by a test root.
+type safetyNetFixture struct {
+	now            time.Time
+	roots          *x509.CertPool
+	rawAuthData    []byte
+	clientDataHash [32]byte
+	payload        safetyNetPayload
+	leafKey        *rsa.PrivateKey
+	leafCert       []byte
+}
+
+func newSafetyNetFixture(t *testing.T) *safetyNetFixture {
+	t.Helper()
+	f := &safetyNetFixture{
+		now:            time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+		clientDataHash: sha256.Sum256([]byte(`{"type":"webauthn.create"}`)),
+	}
+
+	credKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.rawAuthData = authDataWithKey(t, &credKey.PublicKey)
+	nonce := sha256.Sum256(append(append([]byte{}, f.rawAuthData...), f.clientDataHash[:]...))
+	f.payload = safetyNetPayload{
+		Nonce:           base64.StdEncoding.EncodeToString(nonce[:]),
+		TimestampMs:     f.now.UnixNano() / int64(time.Millisecond),
+		ApkPackageName:  "com.google.android.gms",
+		CtsProfileMatch: true,
+		BasicIntegrity:  true,
+	}
+
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test SafetyNet Root"},
+		NotBefore:             f.now.Add(-24 * time.Hour),
+		NotAfter:              f.now.Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTmpl, rootTmpl, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, _ := x509.ParseCertificate(rootDER)
+	f.roots = x509.NewCertPool()
+	f.roots.AddCert(root)
+
+	if f.leafKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		t.Fatal(err)
+	}
+	leafTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: safetyNetHostname},
+		DNSNames:     []string{safetyNetHostname},
+		NotBefore:    f.now.Add(-time.Hour),
+		NotAfter:     f.now.Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if f.leafCert, err = x509.CreateCertificate(rand.Reader, leafTmpl, root, &f.leafKey.PublicKey, rootKey); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func (f *safetyNetFixture) attestationObject(t *testing.T) webauthnif.DecodedAttestationObject {
+	t.Helper()
+	header, _ := json.Marshal(jws.Header{Alg: "RS256", X5c: []string{base64.StdEncoding.EncodeToString(f.leafCert)}})
+	payload, _ := json.Marshal(f.payload)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, f.leafKey, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+
+	return decodeAttestationObject(t, map[string]interface{}{
+		"fmt":      "android-safetynet",
+		"authData": f.rawAuthData,
+		"attStmt": map[string]interface{}{
+			"ver":      "14366018",
+			"response": []byte(response),
+		},
+	})
+}
+
+func (f *safetyNetFixture) config(now time.Time) AndroidSafetyNetConfig {
+	return AndroidSafetyNetConfig{
+		Roots:            f.roots,
+		MaxTimestampSkew: time.Minute,
+		Now:              func() time.Time { return now },
+	}
+}
+
+func TestVerifyAndroidSafetyNet(t *testing.T) {
+	f := newSafetyNetFixture(t)
+	verify := NewAndroidSafetyNetVerifier(f.config(f.now.Add(30 * time.Second)))
+	result, err := verify(f.attestationObject(t), f.clientDataHash)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Type != AttestationTypeBasic {
+		t.Errorf("got attestation type %v, want %v", result.Type, AttestationTypeBasic)
+	}
+}
+
+func TestVerifyAndroidSafetyNetRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(f *safetyNetFixture)
+		now    time.Duration
+	}{
+		{
+			name:   "nonce is not matched",
+			modify: func(f *safetyNetFixture) { f.clientDataHash[0] ^= 0xff },
+		},
+		{
+			name:   "ctsProfileMatch is false",
+			modify: func(f *safetyNetFixture) { f.payload.CtsProfileMatch = false },
+		},
+		{
+			name:   "timestampMs is too old",
+			modify: func(f *safetyNetFixture) {},
+			now:    2 * time.Minute,
+		},
+		{
+			name:   "roots do not contain the issuer",
+			modify: func(f *safetyNetFixture) { f.roots = x509.NewCertPool() },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSafetyNetFixture(t)
+			tt.modify(f)
+			verify := NewAndroidSafetyNetVerifier(f.config(f.now.Add(tt.now)))
+			if _, err := verify(f.attestationObject(t), f.clientDataHash); err == nil {
+				t.Error("expected an error, but got nil")
+			}
+		})
+	}
+}
+
+func TestAssessAndroidSafetyNet(t *testing.T) {
+	f := newSafetyNetFixture(t)
+	trust := Trust{
+		Policy: PolicyRequireFull,
+		// The default provider has no roots for the format, like the trustanchors directory.
+		Anchors: fileTrustAnchors(t, nil),
+		Verifiers: Verifiers{
+			webauthnif.AttestationStatementFormatAndroidSafetyNet: NewAndroidSafetyNetVerifier(f.config(f.now)),
+		},
+	}
+	verify, _ := trust.Verifier(webauthnif.AttestationStatementFormatAndroidSafetyNet)
+	result, err := verify(f.attestationObject(t), f.clientDataHash)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := trust.Assess(webauthnif.AttestationStatementFormatAndroidSafetyNet, make([]byte, 16), *result)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Type != AttestationTypeBasic {
+		t.Errorf("got %v, want %v", got.Type, AttestationTypeBasic)
+	}
+}
@@ -0,0 +1,84 @@
+package attestation
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"encoding/asn1"
+	"github.com/miliya612/webauthn-demo/webauthnif"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	RegisterAttVerifier(webauthnif.AttestationStatementFormatApple, verifyApple)
+}
+
+// idAppleNonce is the OID of the extension which holds the nonce in Apple anonymous attestation certificates.
+var idAppleNonce = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 8, 2}
+
+// appleNonceExtension is the value of the nonce extension.
+type appleNonceExtension struct {
+	Nonce []byte `asn1:"tag:1,explicit"`
+}
+
+// 8.8
+// verifyApple verifies an attestation statement generated by Apple devices.
+// The trust path is verified against the Apple WebAuthn Root CA by Trust.Assess, which is expected as
+// trustanchors/apple.pem. It is available at https://www.apple.com/certificateauthority/private/
+// See https://www.w3.org/TR/webauthn-2/#sctn-apple-anonymous-attestation
+func verifyApple(attObj webauthnif.DecodedAttestationObject, clientDataHash [32]byte) (*Result, error) {
+	// Verify that attStmt is valid CBOR conforming to the syntax defined above and perform CBOR decoding on it to
+	// extract the contained fields.
+	stmt, err := attStmtMap(attObj)
+	if err != nil {
+		return nil, err
+	}
+	x5c, err := x5cFromAttStmt(stmt)
+	if err != nil {
+		return nil, err
+	}
+	if x5c == nil {
+		return nil, errors.New("x5c is missing in attStmt")
+	}
+	credCert := x5c[0]
+
+	// Concatenate authenticatorData and clientDataHash to form nonceToHash.
+	// Perform SHA-256 hash of nonceToHash to produce nonce.
+	nonce := sha256.Sum256(signedData(attObj, clientDataHash))
+
+	// Verify that nonce equals the value of the extension with OID 1.2.840.113635.100.8.2 in credCert.
+	var certNonce []byte
+	for _, ext := range credCert.Extensions {
+		if !ext.Id.Equal(idAppleNonce) {
+			continue
+		}
+		var v appleNonceExtension
+		if _, err := asn1.Unmarshal(ext.Value, &v); err != nil {
+			return nil, errors.Wrap(err, "unable to parse nonce extension")
+		}
+		certNonce = v.Nonce
+	}
+	if certNonce == nil {
+		return nil, errors.New("nonce extension is missing")
+	}
+	if !bytes.Equal(certNonce, nonce[:]) {
+		return nil, errors.New("nonce is not matched with authenticatorData and clientDataHash")
+	}
+
+	// Verify that the credential public key equals the Subject Public Key of credCert.
+	credPubKey, err := credentialPublicKey(attObj)
+	if err != nil {
+		return nil, err
+	}
+	certPubKey, ok := credCert.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !certPubKey.Equal(credPubKey) {
+		return nil, errors.New("credential public key is not matched with the attestation certificate")
+	}
+
+	// If successful, return implementation-specific values representing attestation type Anonymization CA and
+	// attestation trust path x5c.
+	return &Result{
+		Type:      AttestationTypeAnonCA,
+		TrustPath: x5c,
+	}, nil
+}
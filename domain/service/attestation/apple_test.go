@@ -0,0 +1,139 @@
+package attestation
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/miliya612/webauthn-demo/webauthnif"
+)
+
+// appleFixture is an attestation object in the apple format, whose credential certificate is issued by a test CA in
+// place of the Apple WebAuthn Root CA.
+type appleFixture struct {
+	ca             *testCA
+	credKey        *ecdsa.PrivateKey
+	certKey        *ecdsa.PublicKey
+	rawAuthData    []byte
+	clientDataHash [32]byte
+	nonce          []byte
+}
+
+func newAppleFixture(t *testing.T) *appleFixture {
+	t.Helper()
+	f := &appleFixture{
+		ca:             newTestCA(t),
+		clientDataHash: sha256.Sum256([]byte(`{"type":"webauthn.create"}`)),
+	}
+	var err error
+	if f.credKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	f.certKey = &f.credKey.PublicKey
+	f.rawAuthData = authDataWithKey(t, &f.credKey.PublicKey)
+	nonce := sha256.Sum256(append(append([]byte{}, f.rawAuthData...), f.clientDataHash[:]...))
+	f.nonce = nonce[:]
+	return f
+}
+
+func (f *appleFixture) attestationObject(t *testing.T) webauthnif.DecodedAttestationObject {
+	t.Helper()
+	var exts []pkix.Extension
+	if f.nonce != nil {
+		ext, err := asn1.Marshal(appleNonceExtension{Nonce: f.nonce})
+		if err != nil {
+			t.Fatal(err)
+		}
+		exts = append(exts, pkix.Extension{Id: idAppleNonce, Value: ext})
+	}
+	cert := f.ca.issue(t, &x509.Certificate{
+		Subject:         pkix.Name{CommonName: "Test Apple Credential"},
+		ExtraExtensions: exts,
+	}, f.certKey)
+	return decodeAttestationObject(t, map[string]interface{}{
+		"fmt":      "apple",
+		"authData": f.rawAuthData,
+		"attStmt": map[string]interface{}{
+			"x5c": []interface{}{cert, f.ca.cert.Raw},
+		},
+	})
+}
+
+// fileTrustAnchors returns the provider of a trust anchor directory, which has <key>.pem of the root of cas[key].
+func fileTrustAnchors(t *testing.T, cas map[string]*testCA) TrustAnchorProvider {
+	t.Helper()
+	dir := t.TempDir()
+	for key, ca := range cas {
+		root := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})
+		if err := ioutil.WriteFile(filepath.Join(dir, key+".pem"), root, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, err := NewFileTrustAnchorProvider(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestVerifyApple(t *testing.T) {
+	f := newAppleFixture(t)
+	result, err := verifyApple(f.attestationObject(t), f.clientDataHash)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Type != AttestationTypeAnonCA || len(result.TrustPath) != 2 {
+		t.Fatalf("got %v with trust path of length %d, want %v with 2",
+			result.Type, len(result.TrustPath), AttestationTypeAnonCA)
+	}
+
+	// The trust path is verified against trustanchors/apple.pem.
+	trust := Trust{Policy: PolicyRequireFull, Anchors: fileTrustAnchors(t, map[string]*testCA{"apple": f.ca})}
+	assessed, err := trust.Assess(webauthnif.AttestationStatementFormatApple, make([]byte, 16), *result)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if assessed.Type != AttestationTypeAnonCA {
+		t.Errorf("got %v, want %v", assessed.Type, AttestationTypeAnonCA)
+	}
+
+	// Another root is not trusted.
+	trust.Anchors = fileTrustAnchors(t, map[string]*testCA{"apple": newTestCA(t)})
+	if _, err := trust.Assess(webauthnif.AttestationStatementFormatApple, make([]byte, 16), *result); err == nil {
+		t.Error("expected an error for the certificate of another root, but got nil")
+	}
+}
+
+func TestVerifyAppleRejects(t *testing.T) {
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(f *appleFixture)
+	}{
+		{name: "nonce is not matched", modify: func(f *appleFixture) { f.nonce = make([]byte, 32) }},
+		{name: "nonce extension is missing", modify: func(f *appleFixture) { f.nonce = nil }},
+		{name: "credential public key is not matched", modify: func(f *appleFixture) { f.certKey = &other.PublicKey }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAppleFixture(t)
+			tt.modify(f)
+			if _, err := verifyApple(f.attestationObject(t), f.clientDataHash); err == nil {
+				t.Error("expected an error, but got nil")
+			}
+		})
+	}
+}
@@ -1,11 +1,183 @@
 package attestation
 
-import "github.com/miliya612/webauthn-demo/webauthnif"
+import (
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"github.com/miliya612/webauthn-demo/cose"
+	"github.com/miliya612/webauthn-demo/webauthnif"
+	"github.com/pkg/errors"
+	"io/ioutil"
+)
 
-type AttVerifyFunc func(webauthnif.DecodedAttestationObject, [32]byte) error
+// 6.4.3
+// AttestationType represents the attestation type which was used by the authenticator.
+// See https://www.w3.org/TR/webauthn/#sctn-attestation-types
+type AttestationType string
 
-var AttVerifiers = make(map[webauthnif.AttestationStatementFormatIdentifier]AttVerifyFunc)
+const (
+	// AttestationTypeBasic indicates the authenticator's attestation key pair is specific to an authenticator model.
+	AttestationTypeBasic AttestationType = "Basic"
+	// AttestationTypeSelf indicates the authenticator signs with the credential private key itself.
+	AttestationTypeSelf AttestationType = "Self"
+	// AttestationTypeAttCA indicates the attestation key is certified by an Attestation CA.
+	AttestationTypeAttCA AttestationType = "AttCA"
+	// AttestationTypeAnonCA indicates the attestation key is generated per credential by an Anonymization CA.
+	AttestationTypeAnonCA AttestationType = "AnonCA"
+	// AttestationTypeECDAA indicates the authenticator uses Elliptic Curve based Direct Anonymous Attestation.
+	AttestationTypeECDAA AttestationType = "ECDAA"
+	// AttestationTypeNone indicates no attestation information is available.
+	AttestationTypeNone AttestationType = "None"
+)
+
+// Result is the output of an attestation statement format's verification procedure, which is used to assess the
+// attestation trustworthiness.
+type Result struct {
+	// Type is the attestation type which was used.
+	Type AttestationType
+	// TrustPath is the attestation certificate and its chain, in that order. It is empty for Self and None.
+	TrustPath []*x509.Certificate
+	// TrustPathVerified is whether the verification procedure has verified that TrustPath chains up to a trust anchor
+	// of its own, e.g. a root of the SafetyNet service, so that it is not verified against the trust anchors of step
+	// 15 again.
+	TrustPathVerified bool
+}
+
+type AttVerifyFunc func(webauthnif.DecodedAttestationObject, [32]byte) (*Result, error)
+
+// Verifiers are verification procedures keyed by the attestation statement formats they verify.
+type Verifiers map[webauthnif.AttestationStatementFormatIdentifier]AttVerifyFunc
+
+var AttVerifiers = make(Verifiers)
 
 func RegisterAttVerifier(fmt webauthnif.AttestationStatementFormatIdentifier, f AttVerifyFunc) {
 	AttVerifiers[fmt] = f
 }
+
+// attStmtMap converts the CBOR decoded attStmt into a map keyed by string.
+func attStmtMap(attObj webauthnif.DecodedAttestationObject) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	switch stmt := attObj.AttStmt.(type) {
+	case nil:
+	case map[string]interface{}:
+		for k, v := range stmt {
+			m[k] = v
+		}
+	case map[interface{}]interface{}:
+		for k, v := range stmt {
+			key, ok := k.(string)
+			if !ok {
+				return nil, errors.New(fmt.Sprintf("invalid attStmt key: %v", k))
+			}
+			m[key] = v
+		}
+	default:
+		return nil, errors.New("attStmt is not a map")
+	}
+	return m, nil
+}
+
+// algFromAttStmt returns the "alg" member of attStmt.
+func algFromAttStmt(stmt map[string]interface{}) (webauthnif.COSEAlgorithmIdentifier, error) {
+	switch alg := stmt["alg"].(type) {
+	case int64:
+		return webauthnif.COSEAlgorithmIdentifier(alg), nil
+	case uint64:
+		return webauthnif.COSEAlgorithmIdentifier(alg), nil
+	}
+	return 0, errors.New("alg is missing in attStmt")
+}
+
+// x5cFromAttStmt parses the "x5c" member of attStmt. It returns nil when x5c is not present.
+func x5cFromAttStmt(stmt map[string]interface{}) ([]*x509.Certificate, error) {
+	raw, ok := stmt["x5c"]
+	if !ok {
+		return nil, nil
+	}
+	rawCerts, ok := raw.([]interface{})
+	if !ok || len(rawCerts) == 0 {
+		return nil, errors.New("x5c is not a non-empty array")
+	}
+
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, rc := range rawCerts {
+		der, ok := rc.([]byte)
+		if !ok {
+			return nil, errors.New("x5c contains non-bytes element")
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to parse x5c certificate")
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// LoadCertPool reads PEM encoded certificates from path into a new pool.
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, errors.New(fmt.Sprintf("no certificate is found in %v", path))
+	}
+	return pool, nil
+}
+
+// x509SignatureAlgorithm maps a COSE algorithm onto the x509 signature algorithm used to check signatures made by an
+// attestation certificate.
+func x509SignatureAlgorithm(alg webauthnif.COSEAlgorithmIdentifier) (x509.SignatureAlgorithm, error) {
+	switch alg {
+	case webauthnif.COSEAlgorithmIdentifierES256:
+		return x509.ECDSAWithSHA256, nil
+	case webauthnif.COSEAlgorithmIdentifierES384:
+		return x509.ECDSAWithSHA384, nil
+	case webauthnif.COSEAlgorithmIdentifierES512:
+		return x509.ECDSAWithSHA512, nil
+	case webauthnif.COSEAlgorithmIdentifierEdDSA:
+		return x509.PureEd25519, nil
+	case webauthnif.COSEAlgorithmIdentifierRS256:
+		return x509.SHA256WithRSA, nil
+	case webauthnif.COSEAlgorithmIdentifierRS384:
+		return x509.SHA384WithRSA, nil
+	case webauthnif.COSEAlgorithmIdentifierRS512:
+		return x509.SHA512WithRSA, nil
+	case webauthnif.COSEAlgorithmIdentifierPS256:
+		return x509.SHA256WithRSAPSS, nil
+	case webauthnif.COSEAlgorithmIdentifierPS384:
+		return x509.SHA384WithRSAPSS, nil
+	case webauthnif.COSEAlgorithmIdentifierPS512:
+		return x509.SHA512WithRSAPSS, nil
+	}
+	return x509.UnknownSignatureAlgorithm, errors.New(fmt.Sprintf("unsupported algorithm: %d", alg))
+}
+
+// hashFromAlg returns the hash function used by a COSE algorithm.
+func hashFromAlg(alg webauthnif.COSEAlgorithmIdentifier) (crypto.Hash, error) {
+	hash, err := cose.HashFunc(cose.Algorithm(alg))
+	if err != nil {
+		return 0, err
+	}
+	if hash == 0 {
+		return 0, errors.New(fmt.Sprintf("algorithm %d does not use a hash function", alg))
+	}
+	return hash, nil
+}
+
+// signedData returns the binary concatenation of authenticatorData and clientDataHash.
+func signedData(attObj webauthnif.DecodedAttestationObject, clientDataHash [32]byte) []byte {
+	return append(append([]byte{}, attObj.RawAuthData...), clientDataHash[:]...)
+}
+
+// credentialPublicKey converts the credentialPublicKey in the attestedCredentialData in authenticatorData into a
+// crypto.PublicKey.
+func credentialPublicKey(attObj webauthnif.DecodedAttestationObject) (crypto.PublicKey, error) {
+	key := attObj.AuthData.AttestedCredentialData.DecodedCredentialPublicKey
+	if key == nil {
+		return nil, errors.New("credential public key is missing in authenticatorData")
+	}
+	return key.PublicKey()
+}
@@ -0,0 +1,84 @@
+package attestation
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"github.com/miliya612/webauthn-demo/webauthnif"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	RegisterAttVerifier(webauthnif.AttestationStatementFormatFIDOU2F, verifyFIDOU2F)
+}
+
+// 8.6
+// verifyFIDOU2F verifies an attestation statement generated by FIDO U2F authenticators.
+// See https://www.w3.org/TR/webauthn/#fido-u2f-attestation
+func verifyFIDOU2F(attObj webauthnif.DecodedAttestationObject, clientDataHash [32]byte) (*Result, error) {
+	// Verify that attStmt is valid CBOR conforming to the syntax defined above and perform CBOR decoding on it to
+	// extract the contained fields.
+	stmt, err := attStmtMap(attObj)
+	if err != nil {
+		return nil, err
+	}
+	sig, ok := stmt["sig"].([]byte)
+	if !ok {
+		return nil, errors.New("sig is missing in attStmt")
+	}
+
+	// Check that x5c has exactly one element and let attCert be that element. Let certificate public key be the
+	// public key conveyed by attCert. If certificate public key is not an Elliptic Curve (EC) public key over the
+	// P-256 curve, terminate this algorithm and return an appropriate error.
+	x5c, err := x5cFromAttStmt(stmt)
+	if err != nil {
+		return nil, err
+	}
+	if len(x5c) != 1 {
+		return nil, errors.New("x5c must have exactly one element")
+	}
+	attCert := x5c[0]
+	certPubKey, ok := attCert.PublicKey.(*ecdsa.PublicKey)
+	if !ok || certPubKey.Curve != elliptic.P256() {
+		return nil, errors.New("attestation certificate public key must be an EC public key over the P-256 curve")
+	}
+
+	// Extract the claimed rpIdHash from authenticatorData, and the claimed credentialId and credentialPublicKey from
+	// authenticatorData.attestedCredentialData.
+	rpIdHash := attObj.AuthData.RPIDHash
+	credentialID := attObj.AuthData.AttestedCredentialData.CredentialID
+
+	// Convert the COSE_KEY formatted credentialPublicKey (see Section 7 of [RFC8152]) to Raw ANSI X9.62 public key
+	// format (see ALG_KEY_ECC_X962_RAW in Section 3.6.2 Public Key Representation Formats of [FIDO-Registry]).
+	credPubKey, err := credentialPublicKey(attObj)
+	if err != nil {
+		return nil, err
+	}
+	ecCredPubKey, ok := credPubKey.(*ecdsa.PublicKey)
+	if !ok || ecCredPubKey.Curve != elliptic.P256() {
+		return nil, errors.New("credential public key must be an EC public key over the P-256 curve")
+	}
+	// Let publicKeyU2F be the concatenation 0x04 || x || y.
+	publicKeyU2F := []byte{0x04}
+	publicKeyU2F = append(publicKeyU2F, ecCredPubKey.X.FillBytes(make([]byte, 32))...)
+	publicKeyU2F = append(publicKeyU2F, ecCredPubKey.Y.FillBytes(make([]byte, 32))...)
+
+	// Let verificationData be the concatenation of (0x00 || rpIdHash || clientDataHash || credentialId ||
+	// publicKeyU2F) (see Section 4.3 of [FIDO-U2F-Message-Formats]).
+	verificationData := []byte{0x00}
+	verificationData = append(verificationData, rpIdHash...)
+	verificationData = append(verificationData, clientDataHash[:]...)
+	verificationData = append(verificationData, credentialID...)
+	verificationData = append(verificationData, publicKeyU2F...)
+
+	// Verify the sig using verificationData and certificate public key per [SEC1].
+	if err := attCert.CheckSignature(x509.ECDSAWithSHA256, verificationData, sig); err != nil {
+		return nil, errors.Wrap(err, "invalid attestation signature")
+	}
+
+	// If successful, return attestation type Basic with the attestation trust path set to x5c.
+	return &Result{
+		Type:      AttestationTypeBasic,
+		TrustPath: x5c,
+	}, nil
+}
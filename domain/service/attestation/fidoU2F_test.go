@@ -0,0 +1,147 @@
+package attestation
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miliya612/webauthn-demo/webauthnif"
+)
+
+// recorded is an attestation object recorded with the client data it was created for.
+type recorded struct {
+	attObj         webauthnif.DecodedAttestationObject
+	clientDataHash [32]byte
+}
+
+// loadRecorded reads testdata/<name>.json, which holds the base64url encoded attestationObject and clientDataJSON.
+// They are recorded from software authenticators whose attestation certificates are issued by test CAs, so that the
+// signatures can be verified against the recorded client data.
+func loadRecorded(t *testing.T, name string) recorded {
+	t.Helper()
+	b, err := ioutil.ReadFile(filepath.Join("testdata", name+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v struct {
+		AttestationObject string `json:"attestationObject"`
+		ClientDataJSON    string `json:"clientDataJSON"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		t.Fatal(err)
+	}
+	rawAttObj, err := base64.RawURLEncoding.DecodeString(v.AttestationObject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientDataJSON, err := base64.RawURLEncoding.DecodeString(v.ClientDataJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attObj, err := webauthnif.ParseAttestationObject(rawAttObj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return recorded{attObj: *attObj, clientDataHash: sha256.Sum256(clientDataJSON)}
+}
+
+// loadSeeds returns the attestation objects of format in the fuzzing seeds of webauthnif, whose client data is not
+// recorded.
+func loadSeeds(
+	t *testing.T, format webauthnif.AttestationStatementFormatIdentifier) []webauthnif.DecodedAttestationObject {
+	t.Helper()
+	f, err := os.Open(filepath.Join("..", "..", "..", "webauthnif", "testdata", "attestationObjects.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var attObjs []webauthnif.DecodedAttestationObject
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		b, err := base64.RawURLEncoding.DecodeString(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		attObj, err := webauthnif.ParseAttestationObject(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if attObj.Fmt == format {
+			attObjs = append(attObjs, *attObj)
+		}
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(attObjs) == 0 {
+		t.Fatalf("no %v attestation object is found in the seeds", format)
+	}
+	return attObjs
+}
+
+func TestVerifyFIDOU2F(t *testing.T) {
+	r := loadRecorded(t, "fido-u2f")
+	result, err := verifyFIDOU2F(r.attObj, r.clientDataHash)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Type != AttestationTypeBasic {
+		t.Errorf("got attestation type %v, want %v", result.Type, AttestationTypeBasic)
+	}
+	if len(result.TrustPath) != 1 {
+		t.Errorf("got trust path of length %d, want 1", len(result.TrustPath))
+	}
+}
+
+func TestVerifyFIDOU2FRejects(t *testing.T) {
+	r := loadRecorded(t, "fido-u2f")
+	otherHash := r.clientDataHash
+	otherHash[0] ^= 0xff
+	if _, err := verifyFIDOU2F(r.attObj, otherHash); err == nil {
+		t.Error("expected an error for a signature over other client data, but got nil")
+	}
+
+	// The seeds are signed over client data which is not recorded.
+	for i, attObj := range loadSeeds(t, webauthnif.AttestationStatementFormatFIDOU2F) {
+		if _, err := verifyFIDOU2F(attObj, r.clientDataHash); err == nil {
+			t.Errorf("#%d: expected an error for a signature over other client data, but got nil", i)
+		}
+	}
+
+	// An attestation certificate whose key is not over the P-256 curve.
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := newTestCA(t).issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "P-384"}}, &key.PublicKey)
+	digest := sha256.Sum256([]byte("verificationData"))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	attObj := decodeAttestationObject(t, map[string]interface{}{
+		"fmt":      "fido-u2f",
+		"authData": r.attObj.RawAuthData,
+		"attStmt":  map[string]interface{}{"sig": sig, "x5c": []interface{}{cert}},
+	})
+	if _, err := verifyFIDOU2F(attObj, r.clientDataHash); err == nil {
+		t.Error("expected an error for an attestation key over P-384, but got nil")
+	}
+}
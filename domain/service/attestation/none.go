@@ -1,12 +1,29 @@
 package attestation
 
-import "github.com/miliya612/webauthn-demo/webauthnif"
+import (
+	"github.com/miliya612/webauthn-demo/webauthnif"
+	"github.com/pkg/errors"
+)
 
 func init() {
-	RegisterAttVerifier(webauthnif.AttestationStatementFormatPacked, verifyPacked)
+	RegisterAttVerifier(webauthnif.AttestationStatementFormatNone, verifyNone)
 }
 
-func verifyNone(attObj webauthnif.DecodedAttestationObject, clientDataHash [32]byte) error {
-	return nil
-}
+// 8.7
+// verifyNone verifies the none attestation statement format, which is used to replace any authenticator-provided
+// attestation statement when a WebAuthn Relying Party indicates it does not wish to receive attestation information.
+// See https://www.w3.org/TR/webauthn/#none-attestation
+func verifyNone(attObj webauthnif.DecodedAttestationObject, clientDataHash [32]byte) (*Result, error) {
+	// attStmt of the none format is an empty CBOR map.
+	stmt, err := attStmtMap(attObj)
+	if err != nil {
+		return nil, err
+	}
+	if len(stmt) != 0 {
+		return nil, errors.New("attStmt of none format must be empty")
+	}
 
+	// If successful, return implementation-specific values representing attestation type None and an empty trust
+	// path.
+	return &Result{Type: AttestationTypeNone}, nil
+}
@@ -1,11 +1,145 @@
 package attestation
 
-import "github.com/miliya612/webauthn-demo/webauthnif"
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"github.com/miliya612/webauthn-demo/webauthnif"
+	"github.com/pkg/errors"
+)
 
 func init() {
 	RegisterAttVerifier(webauthnif.AttestationStatementFormatPacked, verifyPacked)
 }
 
-func verifyPacked(attObj webauthnif.DecodedAttestationObject, clientDataHash [32]byte) error {
+// idFidoGenCeAAGUID is the OID of the extension which holds the AAGUID of the authenticator.
+var idFidoGenCeAAGUID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}
+
+// 8.2
+// verifyPacked verifies a WebAuthn optimized attestation statement format.
+// See https://www.w3.org/TR/webauthn/#packed-attestation
+func verifyPacked(attObj webauthnif.DecodedAttestationObject, clientDataHash [32]byte) (*Result, error) {
+	// Verify that attStmt is valid CBOR conforming to the syntax defined above and perform CBOR decoding on it to
+	// extract the contained fields.
+	stmt, err := attStmtMap(attObj)
+	if err != nil {
+		return nil, err
+	}
+	alg, err := algFromAttStmt(stmt)
+	if err != nil {
+		return nil, err
+	}
+	sig, ok := stmt["sig"].([]byte)
+	if !ok {
+		return nil, errors.New("sig is missing in attStmt")
+	}
+
+	x5c, err := x5cFromAttStmt(stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	// If x5c is present, this indicates that the attestation type is not ECDAA.
+	if x5c != nil {
+		attestnCert := x5c[0]
+
+		// Verify that sig is a valid signature over the concatenation of authenticatorData and clientDataHash using
+		// the attestation public key in attestnCert with the algorithm specified in alg.
+		sigAlg, err := x509SignatureAlgorithm(alg)
+		if err != nil {
+			return nil, err
+		}
+		if err := attestnCert.CheckSignature(sigAlg, signedData(attObj, clientDataHash), sig); err != nil {
+			return nil, errors.Wrap(err, "invalid attestation signature")
+		}
+
+		// Verify that attestnCert meets the requirements in §8.2.1 Packed Attestation Statement Certificate
+		// Requirements.
+		// If attestnCert contains an extension with OID 1.3.6.1.4.1.45724.1.1.4 (id-fido-gen-ce-aaguid) verify that
+		// the value of this extension matches the aaguid in authenticatorData.
+		if err := verifyPackedCertificate(attestnCert, attObj.AuthData.AttestedCredentialData.AAGUID); err != nil {
+			return nil, err
+		}
+
+		// If successful, return attestation type Basic and attestation trust path x5c.
+		return &Result{
+			Type:      AttestationTypeBasic,
+			TrustPath: x5c,
+		}, nil
+	}
+
+	// If ecdaaKeyId is present, then the attestation type is ECDAA.
+	if _, ok := stmt["ecdaaKeyId"]; ok {
+		return nil, errors.New("ECDAA attestation is not supported")
+	}
+
+	// If neither x5c nor ecdaaKeyId is present, self attestation is in use.
+	// Validate that alg matches the algorithm of the credentialPublicKey in authenticatorData.
+	credentialPublicKey := attObj.AuthData.AttestedCredentialData.DecodedCredentialPublicKey
+	if credentialPublicKey == nil {
+		return nil, errors.New("credential public key is missing in authenticatorData")
+	}
+	if webauthnif.COSEAlgorithmIdentifier(credentialPublicKey.Algorithm()) != alg {
+		return nil, errors.New("alg is not matched with the credential public key")
+	}
+
+	// Verify that sig is a valid signature over the concatenation of authenticatorData and clientDataHash using the
+	// credential public key with alg.
+	if err := credentialPublicKey.Verify(signedData(attObj, clientDataHash), sig); err != nil {
+		return nil, errors.Wrap(err, "invalid self attestation signature")
+	}
+
+	// If successful, return attestation type Self and empty attestation trust path.
+	return &Result{
+		Type: AttestationTypeSelf,
+	}, nil
+}
+
+// 8.2.1
+// verifyPackedCertificate checks the packed attestation statement certificate requirements.
+// See https://www.w3.org/TR/webauthn/#packed-attestation-cert-requirements
+func verifyPackedCertificate(cert *x509.Certificate, aaguid []byte) error {
+	// Version MUST be set to 3 (which is indicated by an ASN.1 INTEGER with value 2).
+	if cert.Version != 3 {
+		return errors.New("attestation certificate version must be 3")
+	}
+
+	// Subject field MUST be set to:
+	//   Subject-C:  ISO 3166 code specifying the country where the Authenticator vendor is incorporated
+	//   Subject-O:  Legal name of the Authenticator vendor
+	//   Subject-OU: Literal string “Authenticator Attestation”
+	//   Subject-CN: A UTF8String of the vendor’s choosing
+	subject := cert.Subject
+	if len(subject.Country) == 0 || len(subject.Organization) == 0 || subject.CommonName == "" {
+		return errors.New("attestation certificate subject is incomplete")
+	}
+	if len(subject.OrganizationalUnit) == 0 || subject.OrganizationalUnit[0] != "Authenticator Attestation" {
+		return errors.New("attestation certificate subject-OU must be \"Authenticator Attestation\"")
+	}
+
+	// If the related attestation root certificate is used for multiple authenticator models, the Extension OID
+	// 1.3.6.1.4.1.45724.1.1.4 (id-fido-gen-ce-aaguid) MUST be present, containing the AAGUID as a 16-byte OCTET
+	// STRING. The extension MUST NOT be marked as critical.
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(idFidoGenCeAAGUID) {
+			continue
+		}
+		if ext.Critical {
+			return errors.New("id-fido-gen-ce-aaguid extension must not be critical")
+		}
+		var certAAGUID []byte
+		if _, err := asn1.Unmarshal(ext.Value, &certAAGUID); err != nil {
+			return errors.Wrap(err, "unable to parse id-fido-gen-ce-aaguid extension")
+		}
+		if !bytes.Equal(certAAGUID, aaguid) {
+			return errors.New("aaguid is not matched with the attestation certificate")
+		}
+	}
+
+	// The Basic Constraints extension MUST have the CA component set to false.
+	if !cert.BasicConstraintsValid || cert.IsCA {
+		return errors.New("attestation certificate must not be a CA")
+	}
+
 	return nil
 }
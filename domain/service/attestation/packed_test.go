@@ -0,0 +1,248 @@
+package attestation
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/miliya612/webauthn-demo/webauthnif"
+)
+
+// testCA is a root certificate authority which issues the attestation certificates of the tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key}
+}
+
+// pool returns a pool which has the root certificate only.
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+// issue returns the DER encoded certificate of pub, which is issued from tmpl.
+func (ca *testCA) issue(t *testing.T, tmpl *x509.Certificate, pub interface{}) []byte {
+	t.Helper()
+	if tmpl.SerialNumber == nil {
+		tmpl.SerialNumber = big.NewInt(2)
+	}
+	if tmpl.NotBefore.IsZero() {
+		tmpl.NotBefore, tmpl.NotAfter = ca.cert.NotBefore, ca.cert.NotAfter
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, pub, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// packedCertificate returns a template which meets the packed attestation statement certificate requirements for the
+// authenticator model identified by aaguid.
+func packedCertificate(t *testing.T, aaguid []byte) *x509.Certificate {
+	t.Helper()
+	ext, err := asn1.Marshal(aaguid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &x509.Certificate{
+		Subject: pkix.Name{
+			Country:            []string{"JP"},
+			Organization:       []string{"webauthn-demo"},
+			OrganizationalUnit: []string{"Authenticator Attestation"},
+			CommonName:         "Test Authenticator",
+		},
+		BasicConstraintsValid: true,
+		ExtraExtensions:       []pkix.Extension{{Id: idFidoGenCeAAGUID, Value: ext}},
+	}
+}
+
+// packedFixture is an attestation object in the packed format, which is signed by the attestation key if certTmpl is
+// not nil, or by the credential private key otherwise.
+type packedFixture struct {
+	ca             *testCA
+	credKey        *ecdsa.PrivateKey
+	attKey         *ecdsa.PrivateKey
+	certTmpl       *x509.Certificate
+	alg            webauthnif.COSEAlgorithmIdentifier
+	rawAuthData    []byte
+	clientDataHash [32]byte
+	// tamper modifies the signature if it is not nil.
+	tamper func(sig []byte)
+}
+
+func newPackedFixture(t *testing.T, full bool) *packedFixture {
+	t.Helper()
+	f := &packedFixture{
+		ca:             newTestCA(t),
+		alg:            webauthnif.COSEAlgorithmIdentifierES256,
+		clientDataHash: sha256.Sum256([]byte(`{"type":"webauthn.create"}`)),
+	}
+	var err error
+	if f.credKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	if f.attKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	f.rawAuthData = authDataWithKey(t, &f.credKey.PublicKey)
+	if full {
+		f.certTmpl = packedCertificate(t, make([]byte, 16))
+	}
+	return f
+}
+
+func (f *packedFixture) attestationObject(t *testing.T) webauthnif.DecodedAttestationObject {
+	t.Helper()
+	key := f.credKey
+	if f.certTmpl != nil {
+		key = f.attKey
+	}
+	digest := sha256.Sum256(append(append([]byte{}, f.rawAuthData...), f.clientDataHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.tamper != nil {
+		f.tamper(sig)
+	}
+
+	stmt := map[string]interface{}{"alg": int64(f.alg), "sig": sig}
+	if f.certTmpl != nil {
+		stmt["x5c"] = []interface{}{f.ca.issue(t, f.certTmpl, &f.attKey.PublicKey)}
+	}
+	return decodeAttestationObject(t, map[string]interface{}{
+		"fmt":      "packed",
+		"authData": f.rawAuthData,
+		"attStmt":  stmt,
+	})
+}
+
+func TestVerifyPacked(t *testing.T) {
+	tests := []struct {
+		name          string
+		full          bool
+		wantType      AttestationType
+		wantTrustPath int
+	}{
+		{name: "self", full: false, wantType: AttestationTypeSelf, wantTrustPath: 0},
+		{name: "full", full: true, wantType: AttestationTypeBasic, wantTrustPath: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newPackedFixture(t, tt.full)
+			result, err := verifyPacked(f.attestationObject(t), f.clientDataHash)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Type != tt.wantType {
+				t.Errorf("got attestation type %v, want %v", result.Type, tt.wantType)
+			}
+			if len(result.TrustPath) != tt.wantTrustPath {
+				t.Errorf("got trust path of length %d, want %d", len(result.TrustPath), tt.wantTrustPath)
+			}
+		})
+	}
+}
+
+func TestVerifyPackedRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		full   bool
+		modify func(t *testing.T, f *packedFixture)
+	}{
+		{
+			name: "self: alg is not matched with the credential public key",
+			modify: func(t *testing.T, f *packedFixture) {
+				f.alg = webauthnif.COSEAlgorithmIdentifierRS256
+			},
+		},
+		{
+			name: "self: bad signature",
+			modify: func(t *testing.T, f *packedFixture) {
+				f.tamper = func(sig []byte) { sig[len(sig)-1] ^= 0xff }
+			},
+		},
+		{
+			name: "full: alg is not matched with the attestation key",
+			full: true,
+			modify: func(t *testing.T, f *packedFixture) {
+				f.alg = webauthnif.COSEAlgorithmIdentifierRS256
+			},
+		},
+		{
+			name: "full: bad signature",
+			full: true,
+			modify: func(t *testing.T, f *packedFixture) {
+				f.tamper = func(sig []byte) { sig[len(sig)-1] ^= 0xff }
+			},
+		},
+		{
+			name: "full: subject-OU is not Authenticator Attestation",
+			full: true,
+			modify: func(t *testing.T, f *packedFixture) {
+				f.certTmpl.Subject.OrganizationalUnit = []string{"Authenticator"}
+			},
+		},
+		{
+			name: "full: aaguid extension is not matched with authenticatorData",
+			full: true,
+			modify: func(t *testing.T, f *packedFixture) {
+				aaguid := make([]byte, 16)
+				aaguid[0] = 1
+				f.certTmpl.ExtraExtensions = packedCertificate(t, aaguid).ExtraExtensions
+			},
+		},
+		{
+			name: "full: basicConstraints CA is true",
+			full: true,
+			modify: func(t *testing.T, f *packedFixture) {
+				f.certTmpl.IsCA = true
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newPackedFixture(t, tt.full)
+			tt.modify(t, f)
+			if _, err := verifyPacked(f.attestationObject(t), f.clientDataHash); err == nil {
+				t.Error("expected an error, but got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package attestation
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+)
+
+// Policy is the Relying Party policy on the attestation trustworthiness of new credentials.
+type Policy string
+
+const (
+	// PolicyAcceptNone accepts credentials without any attestation.
+	PolicyAcceptNone Policy = "none"
+	// PolicyRequireSelf requires at least self attestation, so that the authenticator proves possession of the
+	// credential private key.
+	PolicyRequireSelf Policy = "self"
+	// PolicyRequireFull requires attestation with an attestation certificate which chains up to a trusted root.
+	PolicyRequireFull Policy = "full"
+)
+
+// Check returns an error when the attestation type in result is not acceptable under the policy.
+func (p Policy) Check(result Result) error {
+	switch p {
+	case PolicyAcceptNone:
+		return nil
+	case PolicyRequireSelf:
+		if result.Type == AttestationTypeNone {
+			return errors.New("attestation is required")
+		}
+		return nil
+	case PolicyRequireFull:
+		if result.Type == AttestationTypeNone || result.Type == AttestationTypeSelf {
+			return errors.New(fmt.Sprintf("%v attestation is not acceptable", result.Type))
+		}
+		if len(result.TrustPath) == 0 {
+			return errors.New(fmt.Sprintf("%v attestation has no trust path", result.Type))
+		}
+		return nil
+	}
+	return errors.New(fmt.Sprintf("unknown attestation policy: %q", p))
+}
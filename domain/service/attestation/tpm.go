@@ -0,0 +1,506 @@
+package attestation
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha1"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"fmt"
+	"github.com/miliya612/webauthn-demo/webauthnif"
+	"github.com/pkg/errors"
+	"math/big"
+)
+
+func init() {
+	RegisterAttVerifier(webauthnif.AttestationStatementFormatTPM, verifyTPM)
+}
+
+// TPM constants, see [TPMv2-Part2].
+const (
+	tpmGeneratedValue    uint32 = 0xff544347
+	tpmStAttestCertify   uint16 = 0x8017
+	tpmAlgRSA            uint16 = 0x0001
+	tpmAlgSHA1           uint16 = 0x0004
+	tpmAlgSHA256         uint16 = 0x000B
+	tpmAlgSHA384         uint16 = 0x000C
+	tpmAlgSHA512         uint16 = 0x000D
+	tpmAlgNull           uint16 = 0x0010
+	tpmAlgECC            uint16 = 0x0023
+	tpmEccNistP256       uint16 = 0x0003
+	tpmEccNistP384       uint16 = 0x0004
+	tpmEccNistP521       uint16 = 0x0005
+	tpmDefaultRSAExpnt   uint32 = 65537
+	tpmClockInfoLength          = 17
+	tpmFirmwareVerLength        = 8
+)
+
+var (
+	// tcgKpAIKCertificate is the OID of the extended key usage which AIK certificates MUST have.
+	tcgKpAIKCertificate = asn1.ObjectIdentifier{2, 23, 133, 8, 3}
+	// tcgAtTpmManufacturer, tcgAtTpmModel and tcgAtTpmVersion are the OIDs of the attributes of the directoryName
+	// in the subject alternative name of AIK certificates.
+	tcgAtTpmManufacturer = asn1.ObjectIdentifier{2, 23, 133, 2, 1}
+	tcgAtTpmModel        = asn1.ObjectIdentifier{2, 23, 133, 2, 2}
+	tcgAtTpmVersion      = asn1.ObjectIdentifier{2, 23, 133, 2, 3}
+	// oidSubjectAltName is the OID of the subject alternative name extension.
+	oidSubjectAltName = asn1.ObjectIdentifier{2, 5, 29, 17}
+)
+
+// tpmtPublic is the TPMT_PUBLIC structure conveyed in pubArea, see [TPMv2-Part2] section 12.2.4.
+type tpmtPublic struct {
+	Type             uint16
+	NameAlg          uint16
+	ObjectAttributes uint32
+	AuthPolicy       []byte
+	// CurveID is set only when Type is TPM_ALG_ECC.
+	CurveID uint16
+	// Exponent is set only when Type is TPM_ALG_RSA.
+	Exponent uint32
+	// Unique holds the RSA modulus, or the concatenation of the x and y coordinates of the ECC point.
+	Unique []byte
+	// X and Y are set only when Type is TPM_ALG_ECC.
+	X, Y []byte
+}
+
+// tpmsAttest is the TPMS_ATTEST structure conveyed in certInfo, see [TPMv2-Part2] section 10.12.8.
+type tpmsAttest struct {
+	Magic           uint32
+	Type            uint16
+	QualifiedSigner []byte
+	ExtraData       []byte
+	ClockInfo       []byte
+	FirmwareVersion uint64
+	// Name and QualifiedName are the members of TPMS_CERTIFY_INFO.
+	Name          []byte
+	QualifiedName []byte
+}
+
+// 8.3
+// verifyTPM verifies an attestation statement generated by authenticators which use a Trusted Platform Module.
+// See https://www.w3.org/TR/webauthn/#tpm-attestation
+func verifyTPM(attObj webauthnif.DecodedAttestationObject, clientDataHash [32]byte) (*Result, error) {
+	// Verify that attStmt is valid CBOR conforming to the syntax defined above and perform CBOR decoding on it to
+	// extract the contained fields.
+	stmt, err := attStmtMap(attObj)
+	if err != nil {
+		return nil, err
+	}
+	if ver, _ := stmt["ver"].(string); ver != "2.0" {
+		return nil, errors.New("ver must be \"2.0\"")
+	}
+	alg, err := algFromAttStmt(stmt)
+	if err != nil {
+		return nil, err
+	}
+	sig, ok := stmt["sig"].([]byte)
+	if !ok {
+		return nil, errors.New("sig is missing in attStmt")
+	}
+	rawCertInfo, ok := stmt["certInfo"].([]byte)
+	if !ok {
+		return nil, errors.New("certInfo is missing in attStmt")
+	}
+	rawPubArea, ok := stmt["pubArea"].([]byte)
+	if !ok {
+		return nil, errors.New("pubArea is missing in attStmt")
+	}
+
+	// Verify that the public key specified by the parameters and unique fields of pubArea is identical to the
+	// credentialPublicKey in the attestedCredentialData in authenticatorData.
+	pubArea, err := parseTPMTPublic(rawPubArea)
+	if err != nil {
+		return nil, err
+	}
+	credPubKey, err := credentialPublicKey(attObj)
+	if err != nil {
+		return nil, err
+	}
+	if err := matchTPMPublicKey(pubArea, credPubKey); err != nil {
+		return nil, err
+	}
+
+	// Concatenate authenticatorData and clientDataHash to form attToBeSigned.
+	attToBeSigned := signedData(attObj, clientDataHash)
+
+	// Validate that certInfo is valid:
+	certInfo, err := parseTPMSAttest(rawCertInfo)
+	if err != nil {
+		return nil, err
+	}
+	//   - Verify that magic is set to TPM_GENERATED_VALUE.
+	if certInfo.Magic != tpmGeneratedValue {
+		return nil, errors.New("certInfo.magic must be TPM_GENERATED_VALUE")
+	}
+	//   - Verify that type is set to TPM_ST_ATTEST_CERTIFY.
+	if certInfo.Type != tpmStAttestCertify {
+		return nil, errors.New("certInfo.type must be TPM_ST_ATTEST_CERTIFY")
+	}
+	//   - Verify that extraData is set to the hash of attToBeSigned using the hash algorithm employed in "alg".
+	hash, err := tpmHashFromAlg(alg)
+	if err != nil {
+		return nil, err
+	}
+	h := hash.New()
+	h.Write(attToBeSigned)
+	if !bytes.Equal(certInfo.ExtraData, h.Sum(nil)) {
+		return nil, errors.New("certInfo.extraData is not matched with the hash of attToBeSigned")
+	}
+	//   - Verify that attested contains a TPMS_CERTIFY_INFO structure as specified in [TPMv2-Part2] section 10.12.3,
+	//     whose name field contains a valid Name for pubArea, as computed using the algorithm in the nameAlg field of
+	//     pubArea using the procedure specified in [TPMv2-Part1] section 16.
+	name, err := tpmName(pubArea.NameAlg, rawPubArea)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(certInfo.Name, name) {
+		return nil, errors.New("certInfo.attested.name is not matched with pubArea")
+	}
+
+	// If x5c is present, this indicates that the attestation type is not ECDAA.
+	x5c, err := x5cFromAttStmt(stmt)
+	if err != nil {
+		return nil, err
+	}
+	if x5c == nil {
+		if _, ok := stmt["ecdaaKeyId"]; ok {
+			return nil, errors.New("ECDAA attestation is not supported")
+		}
+		return nil, errors.New("x5c is missing in attStmt")
+	}
+	aikCert := x5c[0]
+
+	// Verify the sig is a valid signature over certInfo using the attestation public key in aikCert with the
+	// algorithm specified in alg.
+	if err := verifyTPMSignature(aikCert, alg, rawCertInfo, sig); err != nil {
+		return nil, errors.Wrap(err, "invalid attestation signature")
+	}
+
+	// Verify that aikCert meets the requirements in §8.3.1 TPM Attestation Statement Certificate Requirements.
+	// If aikCert contains an extension with OID 1.3.6.1.4.1.45724.1.1.4 (id-fido-gen-ce-aaguid) verify that the value
+	// of this extension matches the aaguid in authenticatorData.
+	if err := verifyAIKCertificate(aikCert, attObj.AuthData.AttestedCredentialData.AAGUID); err != nil {
+		return nil, err
+	}
+
+	// If successful, return attestation type AttCA and attestation trust path x5c.
+	return &Result{
+		Type:      AttestationTypeAttCA,
+		TrustPath: x5c,
+	}, nil
+}
+
+// 8.3.1
+// verifyAIKCertificate checks the TPM attestation statement certificate requirements.
+// See https://www.w3.org/TR/webauthn/#tpm-cert-requirements
+func verifyAIKCertificate(cert *x509.Certificate, aaguid []byte) error {
+	// Version MUST be set to 3.
+	if cert.Version != 3 {
+		return errors.New("aik certificate version must be 3")
+	}
+
+	// Subject field MUST be set to empty.
+	if len(cert.Subject.Names) != 0 {
+		return errors.New("aik certificate subject must be empty")
+	}
+
+	// The Subject Alternative Name extension MUST be set as defined in [TPMv2-EK-Profile] section 3.2.9.
+	var san []byte
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidSubjectAltName) {
+			san = ext.Value
+		}
+	}
+	if san == nil {
+		return errors.New("aik certificate has no subject alternative name")
+	}
+	manufacturer, model, version, err := parseTPMDeviceAttributes(san)
+	if err != nil {
+		return err
+	}
+	if manufacturer == "" || model == "" || version == "" {
+		return errors.New("aik certificate subject alternative name lacks TPM manufacturer, model or version")
+	}
+
+	// The Extended Key Usage extension MUST contain the "joint-iso-itu-t(2) internationalorganizations(23) 133
+	// tcg-kp(8) tcg-kp-AIKCertificate(3)" OID.
+	hasAIKUsage := false
+	for _, eku := range cert.UnknownExtKeyUsage {
+		if eku.Equal(tcgKpAIKCertificate) {
+			hasAIKUsage = true
+		}
+	}
+	if !hasAIKUsage {
+		return errors.New("aik certificate extended key usage lacks tcg-kp-AIKCertificate")
+	}
+
+	// The Basic Constraints extension MUST have the CA component set to false.
+	if !cert.BasicConstraintsValid || cert.IsCA {
+		return errors.New("aik certificate must not be a CA")
+	}
+
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(idFidoGenCeAAGUID) {
+			continue
+		}
+		var certAAGUID []byte
+		if _, err := asn1.Unmarshal(ext.Value, &certAAGUID); err != nil {
+			return errors.Wrap(err, "unable to parse id-fido-gen-ce-aaguid extension")
+		}
+		if !bytes.Equal(certAAGUID, aaguid) {
+			return errors.New("aaguid is not matched with the aik certificate")
+		}
+	}
+
+	return nil
+}
+
+// parseTPMDeviceAttributes extracts the TPM manufacturer, model and version from the directoryName of a subject
+// alternative name extension.
+func parseTPMDeviceAttributes(san []byte) (manufacturer, model, version string, err error) {
+	var names []asn1.RawValue
+	if _, err = asn1.Unmarshal(san, &names); err != nil {
+		return "", "", "", errors.Wrap(err, "unable to parse subject alternative name")
+	}
+	for _, n := range names {
+		// directoryName [4] Name
+		if n.Class != asn1.ClassContextSpecific || n.Tag != 4 {
+			continue
+		}
+		var rdns pkix.RDNSequence
+		if _, err = asn1.Unmarshal(n.Bytes, &rdns); err != nil {
+			return "", "", "", errors.Wrap(err, "unable to parse directoryName")
+		}
+		for _, rdn := range rdns {
+			for _, atv := range rdn {
+				value, _ := atv.Value.(string)
+				switch {
+				case atv.Type.Equal(tcgAtTpmManufacturer):
+					manufacturer = value
+				case atv.Type.Equal(tcgAtTpmModel):
+					model = value
+				case atv.Type.Equal(tcgAtTpmVersion):
+					version = value
+				}
+			}
+		}
+	}
+	return manufacturer, model, version, nil
+}
+
+// matchTPMPublicKey checks that the key in pubArea is identical to the credential public key.
+func matchTPMPublicKey(pubArea *tpmtPublic, credPubKey crypto.PublicKey) error {
+	switch pubArea.Type {
+	case tpmAlgRSA:
+		key, ok := credPubKey.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("pubArea type is not matched with the credential public key")
+		}
+		exponent := pubArea.Exponent
+		if exponent == 0 {
+			exponent = tpmDefaultRSAExpnt
+		}
+		if key.N.Cmp(new(big.Int).SetBytes(pubArea.Unique)) != 0 || uint32(key.E) != exponent {
+			return errors.New("pubArea is not matched with the credential public key")
+		}
+	case tpmAlgECC:
+		key, ok := credPubKey.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("pubArea type is not matched with the credential public key")
+		}
+		curves := map[uint16]elliptic.Curve{
+			tpmEccNistP256: elliptic.P256(),
+			tpmEccNistP384: elliptic.P384(),
+			tpmEccNistP521: elliptic.P521(),
+		}
+		if curves[pubArea.CurveID] != key.Curve {
+			return errors.New("pubArea curve is not matched with the credential public key")
+		}
+		if key.X.Cmp(new(big.Int).SetBytes(pubArea.X)) != 0 || key.Y.Cmp(new(big.Int).SetBytes(pubArea.Y)) != 0 {
+			return errors.New("pubArea is not matched with the credential public key")
+		}
+	default:
+		return errors.New(fmt.Sprintf("unsupported pubArea type: %#04x", pubArea.Type))
+	}
+	return nil
+}
+
+// tpmName computes the Name of the object described by pubArea, which is nameAlg || H_nameAlg(pubArea).
+func tpmName(nameAlg uint16, rawPubArea []byte) ([]byte, error) {
+	hashes := map[uint16]crypto.Hash{
+		tpmAlgSHA1:   crypto.SHA1,
+		tpmAlgSHA256: crypto.SHA256,
+		tpmAlgSHA384: crypto.SHA384,
+		tpmAlgSHA512: crypto.SHA512,
+	}
+	hash, ok := hashes[nameAlg]
+	if !ok || !hash.Available() {
+		return nil, errors.New(fmt.Sprintf("unsupported pubArea nameAlg: %#04x", nameAlg))
+	}
+	h := hash.New()
+	h.Write(rawPubArea)
+
+	name := make([]byte, 2)
+	binary.BigEndian.PutUint16(name, nameAlg)
+	return append(name, h.Sum(nil)...), nil
+}
+
+// parseTPMTPublic parses a TPMT_PUBLIC structure.
+func parseTPMTPublic(b []byte) (*tpmtPublic, error) {
+	r := &tpmReader{buf: b}
+	p := &tpmtPublic{
+		Type:             r.uint16(),
+		NameAlg:          r.uint16(),
+		ObjectAttributes: r.uint32(),
+		AuthPolicy:       r.sized(),
+	}
+
+	switch p.Type {
+	case tpmAlgRSA:
+		// TPMS_RSA_PARMS: symmetric, scheme, keyBits, exponent
+		r.symmetric()
+		r.scheme()
+		_ = r.uint16()
+		p.Exponent = r.uint32()
+		// TPM2B_PUBLIC_KEY_RSA
+		p.Unique = r.sized()
+	case tpmAlgECC:
+		// TPMS_ECC_PARMS: symmetric, scheme, curveID, kdf
+		r.symmetric()
+		r.scheme()
+		p.CurveID = r.uint16()
+		r.scheme()
+		// TPMS_ECC_POINT
+		p.X = r.sized()
+		p.Y = r.sized()
+		p.Unique = append(append([]byte{}, p.X...), p.Y...)
+	default:
+		return nil, errors.New(fmt.Sprintf("unsupported pubArea type: %#04x", p.Type))
+	}
+
+	if err := r.finish(); err != nil {
+		return nil, errors.Wrap(err, "invalid pubArea")
+	}
+	return p, nil
+}
+
+// parseTPMSAttest parses a TPMS_ATTEST structure whose attested member is TPMS_CERTIFY_INFO.
+func parseTPMSAttest(b []byte) (*tpmsAttest, error) {
+	r := &tpmReader{buf: b}
+	a := &tpmsAttest{
+		Magic:           r.uint32(),
+		Type:            r.uint16(),
+		QualifiedSigner: r.sized(),
+		ExtraData:       r.sized(),
+		ClockInfo:       r.bytes(tpmClockInfoLength),
+	}
+	if fw := r.bytes(tpmFirmwareVerLength); fw != nil {
+		a.FirmwareVersion = binary.BigEndian.Uint64(fw)
+	}
+	a.Name = r.sized()
+	a.QualifiedName = r.sized()
+
+	if err := r.finish(); err != nil {
+		return nil, errors.Wrap(err, "invalid certInfo")
+	}
+	return a, nil
+}
+
+// tpmReader reads big-endian TPM structures. The first out-of-bounds read is remembered and reported by finish.
+type tpmReader struct {
+	buf []byte
+	err error
+}
+
+func (r *tpmReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > len(r.buf) {
+		r.err = errors.New("unexpected end of data")
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *tpmReader) uint16() uint16 {
+	b := r.bytes(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func (r *tpmReader) uint32() uint32 {
+	b := r.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+// sized reads a TPM2B structure, which is a 16-bit size followed by that many bytes.
+func (r *tpmReader) sized() []byte {
+	return r.bytes(int(r.uint16()))
+}
+
+// tpmHashFromAlg returns the hash function used by alg, which may be RS1 in addition to the algorithms of the other
+// formats.
+func tpmHashFromAlg(alg webauthnif.COSEAlgorithmIdentifier) (crypto.Hash, error) {
+	if alg == webauthnif.COSEAlgorithmIdentifierRS1 {
+		return crypto.SHA1, nil
+	}
+	return hashFromAlg(alg)
+}
+
+// verifyTPMSignature verifies sig over data made by the AIK with alg. RS1 is verified here, since crypto/x509 rejects
+// SHA-1 signatures as insecure. It is accepted for certInfo only, which is signed by many TPMs, e.g. of Windows Hello,
+// with RS1.
+func verifyTPMSignature(aikCert *x509.Certificate, alg webauthnif.COSEAlgorithmIdentifier, data, sig []byte) error {
+	if alg != webauthnif.COSEAlgorithmIdentifierRS1 {
+		sigAlg, err := x509SignatureAlgorithm(alg)
+		if err != nil {
+			return err
+		}
+		return aikCert.CheckSignature(sigAlg, data, sig)
+	}
+	key, ok := aikCert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("RS1 requires an RSA attestation public key")
+	}
+	digest := sha1.Sum(data)
+	return rsa.VerifyPKCS1v15(key, crypto.SHA1, digest[:], sig)
+}
+
+// symmetric reads TPMT_SYM_DEF_OBJECT, whose keyBits and mode are present unless the algorithm is TPM_ALG_NULL.
+func (r *tpmReader) symmetric() {
+	if r.uint16() != tpmAlgNull {
+		r.bytes(4)
+	}
+}
+
+// scheme reads a TPMT_*_SCHEME, whose details are present unless the scheme is TPM_ALG_NULL.
+func (r *tpmReader) scheme() {
+	if r.uint16() != tpmAlgNull {
+		r.bytes(2)
+	}
+}
+
+// finish returns the first error, or an error when trailing bytes remain.
+func (r *tpmReader) finish() error {
+	if r.err != nil {
+		return r.err
+	}
+	if len(r.buf) != 0 {
+		return errors.New("trailing bytes")
+	}
+	return nil
+}
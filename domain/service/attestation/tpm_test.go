@@ -0,0 +1,276 @@
+package attestation
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/miliya612/webauthn-demo/webauthnif"
+	"github.com/ugorji/go/codec"
+)
+
+// tpmFixture is an attestation object in the tpm format, which is produced in software in the same way a TPM does.
+type tpmFixture struct {
+	credKey        *ecdsa.PrivateKey
+	aikKey         *ecdsa.PrivateKey
+	aikCert        []byte
+	rawAuthData    []byte
+	clientDataHash [32]byte
+	pubArea        []byte
+	certInfo       []byte
+}
+
+func newTPMFixture(t *testing.T) *tpmFixture {
+	t.Helper()
+	f := &tpmFixture{clientDataHash: sha256.Sum256([]byte(`{"type":"webauthn.create"}`))}
+
+	var err error
+	if f.credKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	if f.aikKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	f.aikCert = createAIKCertificate(t, f.aikKey, pkix.Name{})
+	f.rawAuthData = authDataWithKey(t, &f.credKey.PublicKey)
+	f.pubArea = eccPubArea(&f.credKey.PublicKey)
+
+	toBeSigned := append(append([]byte{}, f.rawAuthData...), f.clientDataHash[:]...)
+	extraData := sha256.Sum256(toBeSigned)
+	f.certInfo = certifyInfo(extraData[:], f.pubArea)
+	return f
+}
+
+// attestationObject signs certInfo with the AIK and returns the decoded attestation object.
+func (f *tpmFixture) attestationObject(t *testing.T) webauthnif.DecodedAttestationObject {
+	t.Helper()
+	digest := sha256.Sum256(f.certInfo)
+	sig, err := ecdsa.SignASN1(rand.Reader, f.aikKey, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decodeAttestationObject(t, map[string]interface{}{
+		"fmt":      "tpm",
+		"authData": f.rawAuthData,
+		"attStmt": map[string]interface{}{
+			"ver":      "2.0",
+			"alg":      int64(webauthnif.COSEAlgorithmIdentifierES256),
+			"x5c":      []interface{}{f.aikCert},
+			"sig":      sig,
+			"certInfo": f.certInfo,
+			"pubArea":  f.pubArea,
+		},
+	})
+}
+
+func TestVerifyTPM(t *testing.T) {
+	f := newTPMFixture(t)
+	result, err := verifyTPM(f.attestationObject(t), f.clientDataHash)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Type != AttestationTypeAttCA {
+		t.Errorf("got attestation type %v, want %v", result.Type, AttestationTypeAttCA)
+	}
+	if len(result.TrustPath) != 1 {
+		t.Errorf("got trust path of length %d, want 1", len(result.TrustPath))
+	}
+}
+
+// TestVerifyTPMRS1 verifies an attestation object in the shape of Windows Hello, whose credential key is RSA 2048 and
+// whose certInfo is signed by the AIK with RS1.
+func TestVerifyTPMRS1(t *testing.T) {
+	r := loadRecorded(t, "tpm-rs1")
+	result, err := verifyTPM(r.attObj, r.clientDataHash)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Type != AttestationTypeAttCA {
+		t.Errorf("got attestation type %v, want %v", result.Type, AttestationTypeAttCA)
+	}
+
+	otherHash := r.clientDataHash
+	otherHash[0] ^= 0xff
+	if _, err := verifyTPM(r.attObj, otherHash); err == nil {
+		t.Error("expected an error for other client data, but got nil")
+	}
+
+	// RS1 is accepted by the tpm format only.
+	f := newPackedFixture(t, true)
+	f.alg = webauthnif.COSEAlgorithmIdentifierRS1
+	if _, err := verifyPacked(f.attestationObject(t), f.clientDataHash); err == nil {
+		t.Error("expected an error for RS1 in the packed format, but got nil")
+	}
+}
+
+func TestVerifyTPMRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(t *testing.T, f *tpmFixture)
+	}{
+		{
+			name: "extraData is not the hash of attToBeSigned",
+			modify: func(t *testing.T, f *tpmFixture) {
+				f.clientDataHash[0] ^= 0xff
+			},
+		},
+		{
+			name: "pubArea does not match the credential public key",
+			modify: func(t *testing.T, f *tpmFixture) {
+				other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+				f.pubArea = eccPubArea(&other.PublicKey)
+			},
+		},
+		{
+			name: "name in certInfo does not match pubArea",
+			modify: func(t *testing.T, f *tpmFixture) {
+				f.certInfo[len(f.certInfo)-len("qualifiedName")-4] ^= 0xff
+			},
+		},
+		{
+			name: "aik certificate has a subject",
+			modify: func(t *testing.T, f *tpmFixture) {
+				f.aikCert = createAIKCertificate(t, f.aikKey, pkix.Name{CommonName: "aik"})
+			},
+		},
+		{
+			name: "truncated certInfo",
+			modify: func(t *testing.T, f *tpmFixture) {
+				f.certInfo = f.certInfo[:10]
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTPMFixture(t)
+			tt.modify(t, f)
+			if _, err := verifyTPM(f.attestationObject(t), f.clientDataHash); err == nil {
+				t.Error("expected an error, but got nil")
+			}
+		})
+	}
+}
+
+func createAIKCertificate(t *testing.T, key *ecdsa.PrivateKey, subject pkix.Name) []byte {
+	t.Helper()
+	rdns := pkix.RDNSequence{{
+		{Type: tcgAtTpmManufacturer, Value: "id:FFFFF1D0"},
+		{Type: tcgAtTpmModel, Value: "FIDO Alliance Software TPM"},
+		{Type: tcgAtTpmVersion, Value: "id:0001"},
+	}}
+	dirName, err := asn1.Marshal(rdns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	san, err := asn1.Marshal([]asn1.RawValue{
+		{Class: asn1.ClassContextSpecific, Tag: 4, IsCompound: true, Bytes: dirName},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               subject,
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		UnknownExtKeyUsage:    []asn1.ObjectIdentifier{tcgKpAIKCertificate},
+		BasicConstraintsValid: true,
+		ExtraExtensions:       []pkix.Extension{{Id: oidSubjectAltName, Critical: true, Value: san}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// eccPubArea encodes key as TPMT_PUBLIC.
+func eccPubArea(key *ecdsa.PublicKey) []byte {
+	b := new(bytes.Buffer)
+	binary.Write(b, binary.BigEndian, tpmAlgECC)
+	binary.Write(b, binary.BigEndian, tpmAlgSHA256)
+	binary.Write(b, binary.BigEndian, uint32(0x00060472))
+	binary.Write(b, binary.BigEndian, uint16(0))  // authPolicy
+	binary.Write(b, binary.BigEndian, tpmAlgNull) // symmetric
+	binary.Write(b, binary.BigEndian, tpmAlgNull) // scheme
+	binary.Write(b, binary.BigEndian, tpmEccNistP256)
+	binary.Write(b, binary.BigEndian, tpmAlgNull) // kdf
+	writeSized(b, key.X.FillBytes(make([]byte, 32)))
+	writeSized(b, key.Y.FillBytes(make([]byte, 32)))
+	return b.Bytes()
+}
+
+// certifyInfo encodes TPMS_ATTEST, which certifies pubArea.
+func certifyInfo(extraData, pubArea []byte) []byte {
+	name, _ := tpmName(tpmAlgSHA256, pubArea)
+	b := new(bytes.Buffer)
+	binary.Write(b, binary.BigEndian, tpmGeneratedValue)
+	binary.Write(b, binary.BigEndian, tpmStAttestCertify)
+	writeSized(b, []byte("qualifiedSigner"))
+	writeSized(b, extraData)
+	b.Write(make([]byte, tpmClockInfoLength))
+	b.Write(make([]byte, tpmFirmwareVerLength))
+	writeSized(b, name)
+	writeSized(b, []byte("qualifiedName"))
+	return b.Bytes()
+}
+
+func writeSized(b *bytes.Buffer, v []byte) {
+	binary.Write(b, binary.BigEndian, uint16(len(v)))
+	b.Write(v)
+}
+
+// authDataWithKey returns authenticator data with attested credential data holding key.
+func authDataWithKey(t *testing.T, key *ecdsa.PublicKey) []byte {
+	t.Helper()
+	coseKey := encodeCBOR(t, map[int]interface{}{
+		1:  2,
+		3:  int(webauthnif.COSEAlgorithmIdentifierES256),
+		-1: 1,
+		-2: key.X.FillBytes(make([]byte, 32)),
+		-3: key.Y.FillBytes(make([]byte, 32)),
+	})
+	rpIdHash := sha256.Sum256([]byte("localhost"))
+	credentialID := []byte("0123456789abcdef")
+
+	b := new(bytes.Buffer)
+	b.Write(rpIdHash[:])
+	b.WriteByte(webauthnif.AuthenticatorDataFlagUserPresent | webauthnif.AuthenticatorDataFlagHasCredentialData)
+	binary.Write(b, binary.BigEndian, uint32(1))
+	b.Write(make([]byte, 16))
+	writeSized(b, credentialID)
+	b.Write(coseKey)
+	return b.Bytes()
+}
+
+func decodeAttestationObject(t *testing.T, v interface{}) webauthnif.DecodedAttestationObject {
+	t.Helper()
+	var attObj webauthnif.DecodedAttestationObject
+	if err := codec.NewDecoderBytes(encodeCBOR(t, v), &codec.CborHandle{}).Decode(&attObj); err != nil {
+		t.Fatal(err)
+	}
+	if err := attObj.UnmarshalBinary(); err != nil {
+		t.Fatal(err)
+	}
+	return attObj
+}
+
+func encodeCBOR(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	var b []byte
+	if err := codec.NewEncoderBytes(&b, &codec.CborHandle{}).Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,212 @@
+package attestation
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"github.com/miliya612/webauthn-demo/webauthnif"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// TrustAnchorProvider provides the acceptable trust anchors for an attestation, which is step 15 of the registration
+// ceremony.
+type TrustAnchorProvider interface {
+	// TrustAnchors returns the root certificates for the authenticator model identified by aaguid, or for the
+	// attestation statement format when no root is dedicated to the model. It returns nil if there is neither.
+	TrustAnchors(format webauthnif.AttestationStatementFormatIdentifier, aaguid []byte) (*x509.CertPool, error)
+}
+
+// StatusChecker reports whether an authenticator model is known to be compromised, e.g. by the FIDO Metadata Service.
+type StatusChecker interface {
+	// CheckStatus returns an error if the authenticator identified by aaguid must not be trusted. Authenticators
+	// without an AAGUID are identified by the attestation certificate at the head of trustPath, if any.
+	CheckStatus(aaguid []byte, trustPath []*x509.Certificate) error
+}
+
+// fileTrustAnchorProvider is a TrustAnchorProvider backed by a directory of PEM encoded root certificates.
+type fileTrustAnchorProvider struct {
+	pools map[string]*x509.CertPool
+}
+
+// NewFileTrustAnchorProvider loads the root certificates in dir. Every entry of dir is keyed by either an AAGUID in
+// its canonical form (e.g. "f8a011f3-8c0a-4d15-8006-17111f9edc7d") or an attestation statement format identifier
+// (e.g. "packed"):
+//   - dir/<key>.pem holds the roots for key.
+//   - dir/<key>/*.pem hold the roots for key.
+func NewFileTrustAnchorProvider(dir string) (TrustAnchorProvider, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &fileTrustAnchorProvider{pools: make(map[string]*x509.CertPool)}
+	for _, e := range entries {
+		var key string
+		var files []string
+		switch {
+		case e.IsDir():
+			key = e.Name()
+			if files, err = filepath.Glob(filepath.Join(dir, e.Name(), "*.pem")); err != nil {
+				return nil, err
+			}
+		case filepath.Ext(e.Name()) == ".pem":
+			key = strings.TrimSuffix(e.Name(), ".pem")
+			files = []string{filepath.Join(dir, e.Name())}
+		default:
+			continue
+		}
+
+		for _, f := range files {
+			b, err := ioutil.ReadFile(f)
+			if err != nil {
+				return nil, err
+			}
+			pool, ok := p.pools[strings.ToLower(key)]
+			if !ok {
+				pool = x509.NewCertPool()
+				p.pools[strings.ToLower(key)] = pool
+			}
+			if !pool.AppendCertsFromPEM(b) {
+				return nil, errors.New(fmt.Sprintf("no certificate is found in %v", f))
+			}
+		}
+	}
+	return p, nil
+}
+
+func (p *fileTrustAnchorProvider) TrustAnchors(
+	format webauthnif.AttestationStatementFormatIdentifier, aaguid []byte) (*x509.CertPool, error) {
+	if pool, ok := p.pools[FormatAAGUID(aaguid)]; ok {
+		return pool, nil
+	}
+	if pool, ok := p.pools[strings.ToLower(string(format))]; ok {
+		return pool, nil
+	}
+	return nil, nil
+}
+
+// FormatAAGUID returns aaguid in the canonical textual form of UUID.
+func FormatAAGUID(aaguid []byte) string {
+	if len(aaguid) != 16 {
+		return hex.EncodeToString(aaguid)
+	}
+	h := hex.EncodeToString(aaguid)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+// VerifyTrustPath verifies that the attestation certificate, which is the first element of trustPath, chains up to one
+// of roots through the rest of trustPath at time at. format is the attestation statement format trustPath is output
+// by.
+func VerifyTrustPath(
+	format webauthnif.AttestationStatementFormatIdentifier, trustPath []*x509.Certificate, roots *x509.CertPool,
+	at time.Time,
+) error {
+	if len(trustPath) == 0 {
+		return errors.New("trust path is empty")
+	}
+	if roots == nil {
+		return errors.New("no trust anchor is available")
+	}
+
+	leaf := *trustPath[0]
+	// The subject alternative name of AIK certificates only has a directoryName, which crypto/x509 does not handle.
+	// It has been checked by the verification procedure of the tpm format.
+	if format == webauthnif.AttestationStatementFormatTPM {
+		var unhandled []asn1.ObjectIdentifier
+		for _, oid := range leaf.UnhandledCriticalExtensions {
+			if !oid.Equal(oidSubjectAltName) {
+				unhandled = append(unhandled, oid)
+			}
+		}
+		leaf.UnhandledCriticalExtensions = unhandled
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range trustPath[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   at,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return errors.Wrap(err, "attestation certificate does not chain up to a trust anchor")
+	}
+	return nil
+}
+
+// Trust is the Relying Party configuration to assess the attestation trustworthiness.
+type Trust struct {
+	// Policy decides which attestation types are acceptable.
+	Policy Policy
+	// Anchors provides the trust anchors. No attestation chains up to a trust anchor if Anchors is nil.
+	Anchors TrustAnchorProvider
+	// Status rejects authenticators which are known to be compromised. No authenticator is rejected if Status is nil.
+	Status StatusChecker
+	// Now returns the time at which certificate chains are verified.
+	Now func() time.Time
+	// Verifiers replace the verification procedures of AttVerifiers for their formats, e.g. with the ones configured
+	// by the Relying Party.
+	Verifiers Verifiers
+}
+
+// Verifier returns the verification procedure of format, which is looked up in Verifiers and then in AttVerifiers.
+func (t Trust) Verifier(format webauthnif.AttestationStatementFormatIdentifier) (AttVerifyFunc, bool) {
+	if f, ok := t.Verifiers[format]; ok {
+		return f, true
+	}
+	f, ok := AttVerifiers[format]
+	return f, ok
+}
+
+// Assess runs steps 15 and 16 of the registration ceremony on result, which is the output of the verification
+// procedure of format. It returns the attestation type the credential is registered with.
+// An attestation whose trust path does not chain up to a trust anchor is treated as no attestation, which Policy
+// accepts or rejects. The trust path is not looked up in Anchors if the verification procedure has verified it.
+func (t Trust) Assess(
+	format webauthnif.AttestationStatementFormatIdentifier, aaguid []byte, result Result) (*Result, error) {
+	// 15. If validation is successful, obtain a list of acceptable trust anchors (attestation root certificates or
+	// ECDAA-Issuer public keys) for that attestation type and attestation statement format fmt, from a trusted
+	// source or from policy.
+	// 16. Assess the attestation trustworthiness using the outputs of the verification procedure in step 14.
+	// Authenticators known to be compromised are rejected regardless of the attestation type.
+	if t.Status != nil {
+		if err := t.Status.CheckStatus(aaguid, result.TrustPath); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case result.Type == AttestationTypeNone, result.Type == AttestationTypeSelf, result.TrustPathVerified:
+	default:
+		var roots *x509.CertPool
+		if t.Anchors != nil {
+			var err error
+			if roots, err = t.Anchors.TrustAnchors(format, aaguid); err != nil {
+				return nil, err
+			}
+		}
+		now := time.Now
+		if t.Now != nil {
+			now = t.Now
+		}
+		if err := VerifyTrustPath(format, result.TrustPath, roots, now()); err != nil {
+			if t.Policy == PolicyRequireFull {
+				return nil, err
+			}
+			result = Result{Type: AttestationTypeNone}
+		}
+	}
+
+	if err := t.Policy.Check(result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
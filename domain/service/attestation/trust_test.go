@@ -0,0 +1,200 @@
+package attestation
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+
+	"github.com/miliya612/webauthn-demo/webauthnif"
+	"github.com/pkg/errors"
+)
+
+// rejectAll is a StatusChecker which rejects every authenticator.
+type rejectAll struct{}
+
+func (rejectAll) CheckStatus([]byte, []*x509.Certificate) error {
+	return errors.New("authenticator is revoked")
+}
+
+// basicResult returns the result of a basic attestation whose certificate is issued by ca.
+func basicResult(t *testing.T, ca *testCA) Result {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(ca.issue(t, &x509.Certificate{
+		Subject: pkix.Name{CommonName: "Test Attestation"},
+	}, &key.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Result{Type: AttestationTypeBasic, TrustPath: []*x509.Certificate{cert, ca.cert}}
+}
+
+func TestTrustAssess(t *testing.T) {
+	ca := newTestCA(t)
+	anchors := fileTrustAnchors(t, map[string]*testCA{"packed": ca})
+	trusted := basicResult(t, ca)
+	untrusted := basicResult(t, newTestCA(t))
+	self := Result{Type: AttestationTypeSelf}
+
+	tests := []struct {
+		name    string
+		trust   Trust
+		result  Result
+		want    AttestationType
+		wantErr bool
+	}{
+		{
+			name:   "trusted chain",
+			trust:  Trust{Policy: PolicyRequireFull, Anchors: anchors},
+			result: trusted,
+			want:   AttestationTypeBasic,
+		},
+		{
+			name:   "untrusted chain is downgraded to none",
+			trust:  Trust{Policy: PolicyAcceptNone, Anchors: anchors},
+			result: untrusted,
+			want:   AttestationTypeNone,
+		},
+		{
+			name:   "chain without trust anchors is downgraded to none",
+			trust:  Trust{Policy: PolicyAcceptNone},
+			result: trusted,
+			want:   AttestationTypeNone,
+		},
+		{
+			name:    "untrusted chain is rejected by the full policy",
+			trust:   Trust{Policy: PolicyRequireFull, Anchors: anchors},
+			result:  untrusted,
+			wantErr: true,
+		},
+		{
+			name:    "downgraded chain is rejected by the self policy",
+			trust:   Trust{Policy: PolicyRequireSelf, Anchors: anchors},
+			result:  untrusted,
+			wantErr: true,
+		},
+		{
+			name:    "self attestation is rejected by the full policy",
+			trust:   Trust{Policy: PolicyRequireFull, Anchors: anchors},
+			result:  self,
+			wantErr: true,
+		},
+		{
+			name:   "self attestation is accepted by the self policy",
+			trust:  Trust{Policy: PolicyRequireSelf, Anchors: anchors},
+			result: self,
+			want:   AttestationTypeSelf,
+		},
+		{
+			name:    "compromised authenticator",
+			trust:   Trust{Policy: PolicyAcceptNone, Anchors: anchors, Status: rejectAll{}},
+			result:  trusted,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.trust.Assess(webauthnif.AttestationStatementFormatPacked, make([]byte, 16), tt.result)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, but got %v", got.Type)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Type != tt.want {
+				t.Errorf("got %v, want %v", got.Type, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileTrustAnchorProvider(t *testing.T) {
+	aaguid := []byte{0xf8, 0xa0, 0x11, 0xf3, 0x8c, 0x0a, 0x4d, 0x15, 0x80, 0x06, 0x17, 0x11, 0x1f, 0x9e, 0xdc, 0x7d}
+	formatCA, modelCA := newTestCA(t), newTestCA(t)
+	p := fileTrustAnchors(t, map[string]*testCA{
+		"packed":                               formatCA,
+		"f8a011f3-8c0a-4d15-8006-17111f9edc7d": modelCA,
+	})
+
+	tests := []struct {
+		name   string
+		format webauthnif.AttestationStatementFormatIdentifier
+		aaguid []byte
+		want   *x509.CertPool
+	}{
+		{
+			name:   "AAGUID takes precedence over format",
+			format: webauthnif.AttestationStatementFormatPacked,
+			aaguid: aaguid,
+			want:   modelCA.pool(),
+		},
+		{
+			name:   "AAGUID without format",
+			format: webauthnif.AttestationStatementFormatTPM,
+			aaguid: aaguid,
+			want:   modelCA.pool(),
+		},
+		{
+			name:   "format of unknown AAGUID",
+			format: webauthnif.AttestationStatementFormatPacked,
+			aaguid: make([]byte, 16),
+			want:   formatCA.pool(),
+		},
+		{
+			name:   "neither",
+			format: webauthnif.AttestationStatementFormatTPM,
+			aaguid: make([]byte, 16),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.TrustAnchors(tt.format, tt.aaguid)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.want == nil {
+				if got != nil {
+					t.Error("got trust anchors, want none")
+				}
+				return
+			}
+			if got == nil || !got.Equal(tt.want) {
+				t.Error("got other trust anchors")
+			}
+		})
+	}
+}
+
+func TestVerifyTrustPathSubjectAltName(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The AIK certificate has a critical subject alternative name of a directoryName only.
+	aikCert, err := x509.ParseCertificate(createAIKCertificate(t, key, pkix.Name{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(aikCert)
+	trustPath := []*x509.Certificate{aikCert}
+
+	if err := VerifyTrustPath(webauthnif.AttestationStatementFormatTPM, trustPath, roots, time.Now()); err != nil {
+		t.Errorf("unexpected error for the tpm format: %v", err)
+	}
+	if err := VerifyTrustPath(webauthnif.AttestationStatementFormatPacked, trustPath, roots, time.Now()); err == nil {
+		t.Error("expected an error for the unhandled critical extension of the packed format, but got nil")
+	}
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/miliya612/webauthn-demo/config"
+	"github.com/miliya612/webauthn-demo/cose"
+	"github.com/miliya612/webauthn-demo/domain/model"
+	"github.com/miliya612/webauthn-demo/domain/repo"
+	"github.com/miliya612/webauthn-demo/webauthnif"
+	"github.com/pkg/errors"
+)
+
+type AuthenticationService interface {
+	GetOptions(id string) (*webauthnif.CredentialRequestOptions, error)
+	GetUser(userId []byte) (*model.User, error)
+	GetCredential(userId, credentialId, userHandle []byte) (*model.Credential, error)
+	ParseClientData(req webauthnif.AuthenticatorAssertionResponse) (*webauthnif.CollectedClientData, error)
+	ValidateClientData(rawChal []byte, c webauthnif.CollectedClientData) error
+	ParseAuthenticatorData(
+		req webauthnif.AuthenticatorAssertionResponse,
+		d *webauthnif.DecodedAuthenticatorAssertionResponse,
+	) (*webauthnif.DecodedAuthenticatorAssertionResponse, error)
+	ValidateAuthenticatorData(data webauthnif.AuthenticatorData) error
+	ValidateClientExtensionOutputs(outputs webauthnif.AuthenticationExtensionsClientOutputs) error
+	VerifySignature(cred model.Credential, rawAuthData []byte, hashedClientData [32]byte, sig []byte) error
+	UpdateSignCount(cred model.Credential, signCount uint32) error
+}
+
+type authenticationService struct {
+	credentialRepo repo.CredentialRepo
+	userRepo       repo.UserRepo
+	tenantID       string
+	rp             config.RPConfig
+}
+
+// NewAuthenticationService returns an AuthenticationService which runs the authentication ceremony for the Relying
+// Party of tenant. Only the users and credentials registered with the tenant are authenticated.
+func NewAuthenticationService(
+	credential repo.CredentialRepo, user repo.UserRepo, tenant config.Tenant) AuthenticationService {
+	return &authenticationService{
+		credentialRepo: credential,
+		userRepo:       user,
+		tenantID:       tenant.TenantID,
+		rp:             tenant.RPConfig,
+	}
+}
+
+const (
+	ASSERTIONCLIENTDATATYPE string = "webauthn.get"
+)
+
+// GetOptions returns CredentialRequestOptions to client. It will be used when calling navigator.credentials.get().
+// Parameters:
+//   - id: REQUIRED. This param identifies user who will be authenticated by RP.
+func (s authenticationService) GetOptions(id string) (*webauthnif.CredentialRequestOptions, error) {
+	// An unknown user is not told apart from a user without credentials, so that user IDs can not be enumerated.
+	errMsg := "no credential is registered"
+	user, err := s.userRepo.GetByID(s.tenantID, webauthnif.ToBufferSource(id))
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("invalidAuthenticationRequest: %v", errMsg))
+	}
+
+	creds, err := s.credentialRepo.GetByUserID(s.tenantID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(creds) == 0 {
+		return nil, errors.New(fmt.Sprintf("invalidAuthenticationRequest: %v", errMsg))
+	}
+
+	allowCredentials := webauthnif.PublicKeyCredentialDescriptors{}
+	for _, c := range creds {
+		allowCredentials = append(allowCredentials, webauthnif.PublicKeyCredentialDescriptor{
+			Type: webauthnif.PublicKeyCredentialTypePublicKey,
+			ID:   c.CredentialID,
+		})
+	}
+
+	challenge, err := webauthnif.GenChallenge()
+	if err != nil {
+		return nil, err
+	}
+
+	pkoptions := &webauthnif.PublicKeyCredentialRequest{
+		Challenge:        challenge,
+		Timeout:          int(s.rp.Timeout),
+		RPID:             s.rp.ID,
+		AllowCredentials: allowCredentials,
+		UserVerification: s.rp.UserVerification,
+		Extensions:       webauthnif.AuthenticationExtensionsClientInputs{},
+	}
+
+	options := &webauthnif.CredentialRequestOptions{
+		PublicKey: *pkoptions,
+	}
+
+	return options, nil
+}
+
+func (s authenticationService) GetUser(userId []byte) (*model.User, error) {
+	return s.userRepo.GetByID(s.tenantID, userId)
+}
+
+func (s authenticationService) GetCredential(userId, credentialId, userHandle []byte) (*model.Credential, error) {
+	// 3. Using credential’s id attribute (or the corresponding rawId, if base64url encoding is inappropriate for your
+	// use case), look up the corresponding credential public key.
+	cred, err := s.credentialRepo.GetByCredentialID(s.tenantID, credentialId)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("invalidAuthenticationRequest: %v", err))
+	}
+	if cred == nil {
+		errMsg := "credential is not registered"
+		return nil, errors.New(fmt.Sprintf("invalidAuthenticationRequest: %v", errMsg))
+	}
+
+	// 1. If the allowCredentials option was given when this authentication ceremony was initiated, verify that
+	// credential.id identifies one of the public key credentials that were listed in allowCredentials.
+	// 2. If credential.response.userHandle is present, verify that the user identified by this value is the owner of
+	// the public key credential identified by credential.id.
+	// allowCredentials always lists every credential of the user bound to the session, so checking the owner of the
+	// credential covers step 1 as well.
+	if !bytes.Equal(cred.UserID, userId) {
+		errMsg := "credential is not owned by the user"
+		return nil, errors.New(fmt.Sprintf("invalidAuthenticationRequest: %v", errMsg))
+	}
+	if len(userHandle) != 0 && !bytes.Equal(cred.UserID, userHandle) {
+		errMsg := "user handle is not matched with the owner of the credential"
+		return nil, errors.New(fmt.Sprintf("invalidAuthenticationRequest: %v", errMsg))
+	}
+
+	return cred, nil
+}
+
+func (s authenticationService) ParseClientData(req webauthnif.AuthenticatorAssertionResponse) (
+	*webauthnif.CollectedClientData, error) {
+
+	// 6. Let JSONtext be the result of running UTF-8 decode on the value of cData.
+	// 7. Let C, the client data claimed as used for the signature, be the result of running an implementation-specific
+	// JSON parser on JSONtext.
+	c := webauthnif.CollectedClientData{}
+	if err := json.Unmarshal(req.ClientDataJSON, &c); err != nil {
+		return nil, errors.New(fmt.Sprintf("invalidAuthenticationRequest: parsingClientData: %v", err))
+	}
+
+	return &c, nil
+}
+
+func (s authenticationService) ValidateClientData(rawChal []byte, c webauthnif.CollectedClientData) error {
+	// 8. Verify that the value of C.type is the string webauthn.get.
+	if c.Type != ASSERTIONCLIENTDATATYPE {
+		errMsg := fmt.Sprintf("got %q, but %q is required", c.Type, ASSERTIONCLIENTDATATYPE)
+		return errors.New(fmt.Sprintf("invalidAuthenticationRequest: %v", errMsg))
+	}
+
+	// 9. Verify that the value of C.challenge matches the challenge that was sent to the authenticator in the
+	// PublicKeyCredentialRequestOptions passed to the get() call.
+	orgnChallenge := (webauthnif.BufferSource)(rawChal)
+
+	byteChal, err := base64.RawURLEncoding.DecodeString(c.Challenge)
+	if err != nil {
+		return err
+	}
+	challenge := (webauthnif.BufferSource)(byteChal)
+
+	if !challenge.Equals(orgnChallenge) {
+		errMsg := "invalid challenge"
+		return errors.New(fmt.Sprintf("invalidAuthenticationRequest: %v", errMsg))
+	}
+
+	// 10. Verify that the value of C.origin matches the Relying Party's origin.
+	if err := s.rp.CheckOrigin(c.Origin); err != nil {
+		return errors.New(fmt.Sprintf("invalidAuthenticationRequest: %v", err))
+	}
+
+	// If C.topOrigin is present:
+	// 1. Verify that the Relying Party expects this credential to be used within an iframe that is not same-origin
+	// with its ancestors.
+	// 2. Verify that the value of C.topOrigin matches the origin of a page that the Relying Party expects to be
+	// sub-framed within.
+	// C.crossOrigin is checked in the same way, since clients of WebAuthn Level 2 report it without C.topOrigin.
+	if err := s.rp.CheckCrossOrigin(c.CrossOrigin, c.TopOrigin); err != nil {
+		return errors.New(fmt.Sprintf("invalidAuthenticationRequest: %v", err))
+	}
+
+	// 11. Verify that the value of C.tokenBinding.status matches the state of Token Binding for the TLS connection over
+	// which the attestation was obtained. If Token Binding was used on that TLS connection, also verify that
+	// C.tokenBinding.id matches the base64url encoding of the Token Binding ID for the connection.
+	if err := verifyTokenBinding(c.TokenBinding); err != nil {
+		return errors.New(fmt.Sprintf("invalidAuthenticationRequest: %v", err))
+	}
+
+	return nil
+}
+
+func (s authenticationService) ParseAuthenticatorData(
+	req webauthnif.AuthenticatorAssertionResponse,
+	d *webauthnif.DecodedAuthenticatorAssertionResponse,
+) (*webauthnif.DecodedAuthenticatorAssertionResponse, error) {
+	// 5. Let cData, authData and sig denote the value of credential’s response's clientDataJSON, authenticatorData,
+	// and signature respectively.
+	d.RawAuthData = req.AuthenticatorData
+	if err := d.UnmarshalBinary(); err != nil {
+		return nil, errors.New(fmt.Sprintf("invalidAuthenticationRequest: %v", err))
+	}
+
+	return d, nil
+}
+
+func (s authenticationService) ValidateAuthenticatorData(data webauthnif.AuthenticatorData) error {
+	// 12. Verify that the rpIdHash in authData is the SHA-256 hash of the RP ID expected by the Relying Party.
+	wantRpIdHash := sha256.Sum256([]byte(s.rp.ID))
+	if !bytes.Equal(wantRpIdHash[:], data.RPIDHash) {
+		errMsg := "invalid rpIdHash"
+		return errors.New(fmt.Sprintf("invalidAuthenticationRequest: %v", errMsg))
+	}
+
+	// 13. Verify that the User Present bit of the flags in authData is set.
+	if !data.Flags.UserPresent() {
+		errMsg := "no user presentation"
+		return errors.New(fmt.Sprintf("invalidAuthenticationRequest: %v", errMsg))
+	}
+
+	// 14. If user verification is required for this assertion, verify that the User Verified bit of the flags in
+	// authData is set.
+	if s.rp.UserVerificationRequired() {
+		if !data.Flags.UserVerified() {
+			errMsg := "no user verification"
+			return errors.New(fmt.Sprintf("invalidAuthenticationRequest: %v", errMsg))
+		}
+	}
+
+	// If the BE bit of the flags in authData is not set, verify that the BS bit is not set.
+	if !data.Flags.BackupEligible() && data.Flags.BackedUp() {
+		errMsg := "backup state is set on a credential which is not backup eligible"
+		return errors.New(fmt.Sprintf("invalidAuthenticationRequest: %v", errMsg))
+	}
+	return nil
+}
+
+func (s authenticationService) ValidateClientExtensionOutputs(
+	outputs webauthnif.AuthenticationExtensionsClientOutputs) error {
+	// 15. Verify that the values of the client extension outputs in clientExtensionResults and the authenticator
+	// extension outputs in the extensions in authData are as expected, considering the client extension input values
+	// that were given as the extensions option in the get() call. In particular, any extension identifier values in
+	// the clientExtensionResults and the extensions in authData MUST be also be present as extension identifier values
+	// in the extensions member of options, i.e., no extensions are present that were not requested. In the general
+	// case, the meaning of "are as expected" is specific to the Relying Party and which extensions are in use.
+	return nil
+}
+
+func (s authenticationService) VerifySignature(
+	cred model.Credential, rawAuthData []byte, hashedClientData [32]byte, sig []byte) error {
+	// 17. Using the credential public key looked up in step 3, verify that sig is a valid signature over the binary
+	// concatenation of authData and hash.
+	data := append(append([]byte{}, rawAuthData...), hashedClientData[:]...)
+	key, err := cose.Parse(cred.PublicKey)
+	if err != nil {
+		return errors.New(fmt.Sprintf("invalidAuthenticationRequest: %v", err))
+	}
+	if err := key.Verify(data, sig); err != nil {
+		return errors.New(fmt.Sprintf("invalidAuthenticationRequest: %v", err))
+	}
+	return nil
+}
+
+func (s authenticationService) UpdateSignCount(cred model.Credential, signCount uint32) error {
+	// 18. If the signature counter value authData.signCount is nonzero or the value stored in conjunction with
+	// credential’s id attribute is nonzero, then run the following sub-step:
+	//     - If the signature counter value authData.signCount is greater than the signature counter value stored in
+	//     conjunction with credential’s id attribute, update the stored signature counter value, associated with
+	//     credential’s id attribute, to be the value of authData.signCount.
+	//     - less than or equal to the signature counter value stored in conjunction with credential’s id attribute,
+	//     this is a signal that the authenticator may be cloned, i.e. at least two copies of the credential private key
+	//     may exist and are being used in parallel. Relying Parties should incorporate this information into their risk
+	//     scoring. Whether the Relying Party updates the stored signature counter value in this case, or not, or fails
+	//     the authentication ceremony or not, is Relying Party-specific.
+	if signCount == 0 && cred.SignCount == 0 {
+		return nil
+	}
+	errMsg := "signature counter is not increased, the authenticator may be cloned"
+	if signCount <= cred.SignCount {
+		return errors.New(fmt.Sprintf("invalidAuthenticationRequest: %v", errMsg))
+	}
+
+	// The counter is compared with the stored one again when it is updated, since another assertion with the same
+	// counter may have been verified in the meantime.
+	updated, err := s.credentialRepo.UpdateSignCount(s.tenantID, cred.CredentialID, signCount)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return errors.New(fmt.Sprintf("invalidAuthenticationRequest: %v", errMsg))
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/miliya612/webauthn-demo/config"
+	"github.com/miliya612/webauthn-demo/domain/model"
+	"github.com/miliya612/webauthn-demo/domain/service/attestation"
+	"github.com/miliya612/webauthn-demo/infra/persistance/memory"
+	"github.com/miliya612/webauthn-demo/webauthnif"
+)
+
+func newTestAuthenticationService(t *testing.T) (AuthenticationService, *memory.DB) {
+	t.Helper()
+	db := memory.NewDB()
+	tenant := config.Tenant{TenantID: config.DefaultTenantID, RPConfig: config.Default()}
+	return NewAuthenticationService(memory.NewCredentialRepo(db), memory.NewUserRepo(db), tenant), db
+}
+
+func TestGetOptions(t *testing.T) {
+	s, db := newTestAuthenticationService(t)
+	user := model.User{TenantID: config.DefaultTenantID, ID: []byte("alice"), Name: "alice"}
+	if _, err := memory.NewUserRepo(db).Create(user); err != nil {
+		t.Fatal(err)
+	}
+
+	_, unknown := s.GetOptions("bob")
+	_, noCredential := s.GetOptions(string(user.ID))
+	if unknown == nil || noCredential == nil {
+		t.Fatalf("got %v and %v, want errors", unknown, noCredential)
+	}
+	if unknown.Error() != noCredential.Error() {
+		t.Errorf("got %q for an unknown user, want %q to not tell it apart", unknown, noCredential)
+	}
+}
+
+func TestUpdateSignCount(t *testing.T) {
+	s, db := newTestAuthenticationService(t)
+	user := model.User{TenantID: config.DefaultTenantID, ID: []byte("alice"), Name: "alice"}
+	if _, err := memory.NewUserRepo(db).Create(user); err != nil {
+		t.Fatal(err)
+	}
+	cred := model.Credential{TenantID: config.DefaultTenantID, CredentialID: []byte{1}, UserID: user.ID, SignCount: 1}
+	if _, err := memory.NewCredentialRepo(db).Create(cred); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two assertions with the same counter are verified against the credential looked up before either is stored.
+	if err := s.UpdateSignCount(cred, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.UpdateSignCount(cred, 2); err == nil {
+		t.Error("expected an error for the second assertion with the same counter, but got nil")
+	}
+}
+
+// TestValidateAuthenticatorDataUserVerification checks that the UV flag is read from bit 2 of the flags, so that
+// authenticator data of a user who is only present is rejected when user verification is required.
+func TestValidateAuthenticatorDataUserVerification(t *testing.T) {
+	rp := config.Default()
+	rp.UserVerification = webauthnif.UserVerificationRequirementRequired
+	tenant := config.Tenant{TenantID: config.DefaultTenantID, RPConfig: rp}
+	db := memory.NewDB()
+	authn := NewAuthenticationService(memory.NewCredentialRepo(db), memory.NewUserRepo(db), tenant)
+	reg := NewRegistrationService(
+		memory.NewCredentialRepo(db), memory.NewUserRepo(db), memory.NewSessionRepo(db), attestation.Trust{}, tenant)
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+
+	tests := []struct {
+		name    string
+		flags   byte
+		wantErr bool
+	}{
+		{name: "UP and UV", flags: 0x05},
+		{name: "UP only", flags: 0x01, wantErr: true},
+		// Bit 1 is reserved for future use.
+		{name: "UP and RFU1", flags: 0x03, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := webauthnif.ParseAuthenticatorData(append(rpIDHash[:], tt.flags, 0, 0, 0, 1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := authn.ValidateAuthenticatorData(*data); (err != nil) != tt.wantErr {
+				t.Errorf("authentication: got error %v, want error %v", err, tt.wantErr)
+			}
+			// Authenticator data of a registration is rejected for the missing credential public key otherwise.
+			if err := reg.ValidateAuthenticatorData(*data); tt.wantErr && err == nil {
+				t.Error("registration: expected an error, but got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"fmt"
+	"github.com/miliya612/webauthn-demo/webauthnif"
+	"github.com/pkg/errors"
+)
+
+// verifyTokenBinding verifies the token binding state the client reported against the TLS connection the client data
+// was sent over. The server never negotiates Token Binding, so the client may report that it supports token binding,
+// but not that token binding was used.
+func verifyTokenBinding(tb *webauthnif.TokenBinding) error {
+	if tb == nil {
+		return nil
+	}
+	switch tb.Status {
+	case webauthnif.TokenBindingStatusSupported:
+		return nil
+	case webauthnif.TokenBindingStatusPresent:
+		return errors.New("token binding is present, but it was not used on the connection")
+	default:
+		return errors.New(fmt.Sprintf("invalid token binding status: %q", tb.Status))
+	}
+}
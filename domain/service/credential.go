@@ -0,0 +1,51 @@
+package service
+
+import (
+	"fmt"
+	"github.com/miliya612/webauthn-demo/cose"
+	"github.com/miliya612/webauthn-demo/domain/repo"
+	"github.com/miliya612/webauthn-demo/webauthnif"
+	"github.com/pkg/errors"
+)
+
+type CredentialService interface {
+	// GetJWKS returns the public keys of the credentials registered to the user identified by id, so that the other
+	// services can verify the assertions forwarded to them. The keys of an unknown user are empty as well as the ones
+	// of a user without credentials, so that the registered users can not be enumerated.
+	GetJWKS(id string) (*cose.JWKS, error)
+}
+
+type credentialService struct {
+	credentialRepo repo.CredentialRepo
+	tenantID       string
+}
+
+// NewCredentialService returns a CredentialService which serves the credentials registered with the tenant
+// identified by tenantID.
+func NewCredentialService(credential repo.CredentialRepo, tenantID string) CredentialService {
+	return &credentialService{
+		credentialRepo: credential,
+		tenantID:       tenantID,
+	}
+}
+
+func (s credentialService) GetJWKS(id string) (*cose.JWKS, error) {
+	creds, err := s.credentialRepo.GetByUserID(s.tenantID, webauthnif.ToBufferSource(id))
+	if err != nil {
+		return nil, err
+	}
+
+	jwks := &cose.JWKS{Keys: []cose.JWK{}}
+	for _, c := range creds {
+		key, err := cose.Parse(c.PublicKey)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("invalid public key of credential %x", c.CredentialID))
+		}
+		jwk, err := cose.NewJWK(key, c.CredentialID)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("invalid public key of credential %x", c.CredentialID))
+		}
+		jwks.Keys = append(jwks.Keys, *jwk)
+	}
+	return jwks, nil
+}
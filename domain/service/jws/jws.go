@@ -0,0 +1,112 @@
+package jws
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"math/big"
+	"strings"
+)
+
+// Header is the JOSE header of a JWS signed with a certificate chain.
+type Header struct {
+	Alg string   `json:"alg"`
+	X5c []string `json:"x5c"`
+}
+
+// JWS is a JSON Web Signature in compact serialization, see RFC 7515.
+type JWS struct {
+	Header  Header
+	Payload []byte
+	// Certificates is the parsed x5c of Header. The first element is the certificate which signed the JWS.
+	Certificates []*x509.Certificate
+
+	signingInput []byte
+	signature    []byte
+}
+
+// Parse decodes a JWS in compact serialization. It does not verify the signature.
+func Parse(token string) (*JWS, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return nil, errors.New("not a JWS in compact serialization")
+	}
+
+	j := &JWS{signingInput: []byte(parts[0] + "." + parts[1])}
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid header")
+	}
+	if err := json.Unmarshal(rawHeader, &j.Header); err != nil {
+		return nil, errors.Wrap(err, "invalid header")
+	}
+	if j.Payload, err = base64.RawURLEncoding.DecodeString(parts[1]); err != nil {
+		return nil, errors.Wrap(err, "invalid payload")
+	}
+	if j.signature, err = base64.RawURLEncoding.DecodeString(parts[2]); err != nil {
+		return nil, errors.Wrap(err, "invalid signature")
+	}
+
+	for _, c := range j.Header.X5c {
+		der, err := base64.StdEncoding.DecodeString(c)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid x5c in header")
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to parse x5c certificate")
+		}
+		j.Certificates = append(j.Certificates, cert)
+	}
+	return j, nil
+}
+
+// VerifyChain verifies that the signing certificate chains up to opts.Roots through the rest of x5c, and that the JWS
+// is signed by it. opts.Intermediates is overwritten by x5c.
+func (j *JWS) VerifyChain(opts x509.VerifyOptions) error {
+	if len(j.Certificates) == 0 {
+		return errors.New("x5c is missing in header")
+	}
+	opts.Intermediates = x509.NewCertPool()
+	for _, c := range j.Certificates[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+	if _, err := j.Certificates[0].Verify(opts); err != nil {
+		return errors.Wrap(err, "invalid certificate chain")
+	}
+	return j.Verify(j.Certificates[0].PublicKey)
+}
+
+// Verify verifies the signature with pubKey. Only the RS256 and ES256 algorithms are supported.
+func (j *JWS) Verify(pubKey crypto.PublicKey) error {
+	digest := sha256.Sum256(j.signingInput)
+	switch j.Header.Alg {
+	case "RS256":
+		key, ok := pubKey.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("alg is not matched with the public key")
+		}
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], j.signature); err != nil {
+			return errors.Wrap(err, "invalid signature")
+		}
+	case "ES256":
+		key, ok := pubKey.(*ecdsa.PublicKey)
+		if !ok || len(j.signature) != 64 {
+			return errors.New("alg is not matched with the public key")
+		}
+		r := new(big.Int).SetBytes(j.signature[:32])
+		s := new(big.Int).SetBytes(j.signature[32:])
+		if !ecdsa.Verify(key, digest[:], r, s) {
+			return errors.New("invalid signature")
+		}
+	default:
+		return errors.New(fmt.Sprintf("unsupported alg: %v", j.Header.Alg))
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package metadata
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"github.com/miliya612/webauthn-demo/domain/service/jws"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// BLOB is the payload of the FIDO Metadata Service BLOB.
+// See https://fidoalliance.org/specs/mds/fido-metadata-service-v3.0-ps-20210518.html#metadata-blob-payload-dictionary
+type BLOB struct {
+	LegalHeader string  `json:"legalHeader"`
+	No          int     `json:"no"`
+	NextUpdate  string  `json:"nextUpdate"`
+	Entries     []Entry `json:"entries"`
+}
+
+// Entry is an entry of the BLOB which describes one authenticator model.
+type Entry struct {
+	AAID                                 string         `json:"aaid,omitempty"`
+	AAGUID                               string         `json:"aaguid,omitempty"`
+	AttestationCertificateKeyIdentifiers []string       `json:"attestationCertificateKeyIdentifiers,omitempty"`
+	MetadataStatement                    *Statement     `json:"metadataStatement,omitempty"`
+	StatusReports                        []StatusReport `json:"statusReports"`
+	TimeOfLastStatusChange               string         `json:"timeOfLastStatusChange"`
+}
+
+// Statement is the metadata statement of an authenticator model. Only the fields the Relying Party uses are decoded.
+// See https://fidoalliance.org/specs/mds/fido-metadata-statement-v3.0-ps-20210518.html
+type Statement struct {
+	Description                 string   `json:"description"`
+	AuthenticatorVersion        int      `json:"authenticatorVersion"`
+	ProtocolFamily              string   `json:"protocolFamily"`
+	Schema                      int      `json:"schema"`
+	AttestationTypes            []string `json:"attestationTypes"`
+	AttestationRootCertificates []string `json:"attestationRootCertificates"`
+}
+
+// StatusReport is a status of an authenticator model reported by the FIDO Alliance or the vendor.
+type StatusReport struct {
+	Status        AuthenticatorStatus `json:"status"`
+	EffectiveDate string              `json:"effectiveDate,omitempty"`
+	// Certificate is the base64 encoded DER of the attestation certificate a ATTESTATION_KEY_COMPROMISE report is
+	// limited to. The report applies to every attestation certificate of the model if Certificate is empty.
+	Certificate string `json:"certificate,omitempty"`
+	URL         string `json:"url,omitempty"`
+}
+
+// LatestStatusReport returns the status report with the latest effectiveDate. Reports which have the same date are
+// ordered as in the BLOB. It returns nil if the entry has no status report.
+func (e Entry) LatestStatusReport() *StatusReport {
+	var latest *StatusReport
+	for i := range e.StatusReports {
+		r := &e.StatusReports[i]
+		// effectiveDate is an ISO 8601 date, which is ordered as a string.
+		if latest == nil || r.EffectiveDate >= latest.EffectiveDate {
+			latest = r
+		}
+	}
+	return latest
+}
+
+// nextUpdateLayout is the layout of nextUpdate, which is an ISO 8601 date.
+const nextUpdateLayout = "2006-01-02"
+
+// Parse verifies the BLOB in the compact JWS serialization and decodes its payload. The BLOB has to be signed by a
+// certificate which chains up to one of roots at time now, and must not be past its nextUpdate date, after which the
+// status reports may be outdated.
+func Parse(token []byte, roots *x509.CertPool, now time.Time) (*BLOB, error) {
+	if roots == nil {
+		return nil, errors.New("no root certificate of the metadata service is configured")
+	}
+	j, err := jws.Parse(string(token))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid metadata BLOB")
+	}
+	if err := j.VerifyChain(x509.VerifyOptions{Roots: roots, CurrentTime: now}); err != nil {
+		return nil, errors.Wrap(err, "invalid metadata BLOB")
+	}
+
+	blob := &BLOB{}
+	if err := json.Unmarshal(j.Payload, blob); err != nil {
+		return nil, errors.Wrap(err, "invalid metadata BLOB payload")
+	}
+	nextUpdate, err := time.Parse(nextUpdateLayout, blob.NextUpdate)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid nextUpdate of metadata BLOB")
+	}
+	// The BLOB is valid through the nextUpdate date.
+	if !now.Before(nextUpdate.AddDate(0, 0, 1)) {
+		errMsg := fmt.Sprintf("metadata BLOB expired on %v; run \"metadata refresh\"", blob.NextUpdate)
+		return nil, errors.New(errMsg)
+	}
+	return blob, nil
+}
+
+// LoadFile reads and verifies the BLOB at path. See Parse.
+func LoadFile(path string, roots *x509.CertPool, now time.Time) (*BLOB, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b, roots, now)
+}
+
+// Refresh verifies the BLOB at src and replaces the cached BLOB at cache with it. A BLOB whose serial number is lower
+// than the cached one is rejected, so that a stale BLOB can not roll back status reports.
+func Refresh(src, cache string, roots *x509.CertPool, now time.Time) (*BLOB, error) {
+	b, err := ioutil.ReadFile(src)
+	if err != nil {
+		return nil, err
+	}
+	blob, err := Parse(b, roots, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// The cached BLOB was verified when it was cached, and its certificate may have expired since then.
+	cachedNo, err := serialNumber(cache)
+	switch {
+	case os.IsNotExist(errors.Cause(err)):
+	case err != nil:
+		return nil, errors.Wrap(err, "unable to load the cached metadata BLOB")
+	case blob.No < cachedNo:
+		errMsg := fmt.Sprintf("serial number %v is older than the cached one %v", blob.No, cachedNo)
+		return nil, errors.New(errMsg)
+	}
+
+	// The BLOB is written to a temporary file first, so that the cache is never left partially written.
+	tmp, err := ioutil.TempFile(filepath.Dir(cache), filepath.Base(cache)+".*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), cache); err != nil {
+		return nil, err
+	}
+	return blob, nil
+}
+
+// serialNumber returns the serial number of the BLOB at path without verifying it.
+func serialNumber(path string) (int, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	j, err := jws.Parse(string(b))
+	if err != nil {
+		return 0, err
+	}
+	var blob BLOB
+	if err := json.Unmarshal(j.Payload, &blob); err != nil {
+		return 0, err
+	}
+	return blob.No, nil
+}
@@ -0,0 +1,42 @@
+package metadata
+
+// AuthenticatorStatus is the status of an authenticator model in a status report.
+// See https://fidoalliance.org/specs/mds/fido-metadata-service-v3.0-ps-20210518.html#authenticatorstatus-enum
+type AuthenticatorStatus string
+
+const (
+	StatusNotFIDOCertified          AuthenticatorStatus = "NOT_FIDO_CERTIFIED"
+	StatusFIDOCertified             AuthenticatorStatus = "FIDO_CERTIFIED"
+	StatusUserVerificationBypass    AuthenticatorStatus = "USER_VERIFICATION_BYPASS"
+	StatusAttestationKeyCompromise  AuthenticatorStatus = "ATTESTATION_KEY_COMPROMISE"
+	StatusUserKeyRemoteCompromise   AuthenticatorStatus = "USER_KEY_REMOTE_COMPROMISE"
+	StatusUserKeyPhysicalCompromise AuthenticatorStatus = "USER_KEY_PHYSICAL_COMPROMISE"
+	StatusUpdateAvailable           AuthenticatorStatus = "UPDATE_AVAILABLE"
+	StatusRevoked                   AuthenticatorStatus = "REVOKED"
+	StatusSelfAssertionSubmitted    AuthenticatorStatus = "SELF_ASSERTION_SUBMITTED"
+	StatusFIDOCertifiedL1           AuthenticatorStatus = "FIDO_CERTIFIED_L1"
+	StatusFIDOCertifiedL1Plus       AuthenticatorStatus = "FIDO_CERTIFIED_L1plus"
+	StatusFIDOCertifiedL2           AuthenticatorStatus = "FIDO_CERTIFIED_L2"
+	StatusFIDOCertifiedL2Plus       AuthenticatorStatus = "FIDO_CERTIFIED_L2plus"
+	StatusFIDOCertifiedL3           AuthenticatorStatus = "FIDO_CERTIFIED_L3"
+	StatusFIDOCertifiedL3Plus       AuthenticatorStatus = "FIDO_CERTIFIED_L3plus"
+)
+
+// IsCompromised reports whether the status means that the authenticator model must not be trusted.
+func (s AuthenticatorStatus) IsCompromised() bool {
+	switch s {
+	case StatusRevoked,
+		StatusUserVerificationBypass,
+		StatusAttestationKeyCompromise,
+		StatusUserKeyRemoteCompromise,
+		StatusUserKeyPhysicalCompromise:
+		return true
+	}
+	return false
+}
+
+// IsPermanent reports whether the status rejects the authenticator model regardless of later status reports, since no
+// update can fix it.
+func (s AuthenticatorStatus) IsPermanent() bool {
+	return s == StatusRevoked || s == StatusAttestationKeyCompromise
+}
@@ -0,0 +1,134 @@
+package metadata
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/miliya612/webauthn-demo/domain/service/attestation"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// Store is an index of the entries of a BLOB.
+type Store interface {
+	// Entry returns the entry of the authenticator model identified by aaguid, or nil if there is no such entry.
+	Entry(aaguid []byte) *Entry
+	// EntryByKeyIdentifier returns the entry which lists keyIdentifier in attestationCertificateKeyIdentifiers, or nil
+	// if there is no such entry. See KeyIdentifier.
+	EntryByKeyIdentifier(keyIdentifier string) *Entry
+	// CheckStatus returns an error if the authenticator model has been revoked or its attestation key compromised, or
+	// if the latest status report means it is compromised otherwise, e.g. USER_VERIFICATION_BYPASS which an update
+	// can fix. Authenticators which are not listed in the BLOB are not rejected.
+	CheckStatus(aaguid []byte, trustPath []*x509.Certificate) error
+}
+
+type store struct {
+	byAAGUID        map[string]*Entry
+	byKeyIdentifier map[string]*Entry
+}
+
+// NewStore indexes the entries of blob by AAGUID and attestation certificate key identifier.
+func NewStore(blob *BLOB) Store {
+	s := &store{
+		byAAGUID:        make(map[string]*Entry),
+		byKeyIdentifier: make(map[string]*Entry),
+	}
+	for i := range blob.Entries {
+		e := &blob.Entries[i]
+		if e.AAGUID != "" {
+			s.byAAGUID[strings.ToLower(e.AAGUID)] = e
+		}
+		for _, id := range e.AttestationCertificateKeyIdentifiers {
+			s.byKeyIdentifier[strings.ToLower(id)] = e
+		}
+	}
+	return s
+}
+
+func (s *store) Entry(aaguid []byte) *Entry {
+	return s.byAAGUID[attestation.FormatAAGUID(aaguid)]
+}
+
+func (s *store) EntryByKeyIdentifier(keyIdentifier string) *Entry {
+	return s.byKeyIdentifier[strings.ToLower(keyIdentifier)]
+}
+
+func (s *store) CheckStatus(aaguid []byte, trustPath []*x509.Certificate) error {
+	// Authenticators without an AAGUID, e.g. FIDO U2F authenticators, are zero filled.
+	var entry *Entry
+	if len(aaguid) > 0 && !bytes.Equal(aaguid, make([]byte, len(aaguid))) {
+		entry = s.Entry(aaguid)
+	}
+	if entry == nil && len(trustPath) > 0 {
+		keyIdentifier, err := KeyIdentifier(trustPath[0])
+		if err != nil {
+			return err
+		}
+		entry = s.EntryByKeyIdentifier(keyIdentifier)
+	}
+	if entry == nil {
+		return nil
+	}
+
+	// A ATTESTATION_KEY_COMPROMISE report with a certificate only applies to that attestation certificate.
+	applicable := Entry{}
+	for _, r := range entry.StatusReports {
+		if r.Status == StatusAttestationKeyCompromise && r.Certificate != "" && !inTrustPath(r.Certificate, trustPath) {
+			continue
+		}
+		applicable.StatusReports = append(applicable.StatusReports, r)
+	}
+	// REVOKED and ATTESTATION_KEY_COMPROMISE are not lifted by later reports, e.g. UPDATE_AVAILABLE.
+	var report *StatusReport
+	for i := range applicable.StatusReports {
+		if applicable.StatusReports[i].Status.IsPermanent() {
+			report = &applicable.StatusReports[i]
+			break
+		}
+	}
+	if report == nil {
+		report = applicable.LatestStatusReport()
+	}
+	if report == nil || !report.Status.IsCompromised() {
+		return nil
+	}
+
+	name := entry.AAGUID
+	if entry.MetadataStatement != nil && entry.MetadataStatement.Description != "" {
+		name = entry.MetadataStatement.Description
+	}
+	return errors.New(fmt.Sprintf("authenticator %q is reported as %v since %v", name, report.Status, report.EffectiveDate))
+}
+
+// inTrustPath reports whether the base64 encoded certificate is one of trustPath.
+func inTrustPath(certificate string, trustPath []*x509.Certificate) bool {
+	der, err := base64.StdEncoding.DecodeString(certificate)
+	if err != nil {
+		return false
+	}
+	for _, c := range trustPath {
+		if bytes.Equal(c.Raw, der) {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyIdentifier returns the attestation certificate key identifier of cert, which is the hex encoded SHA-1 hash of
+// the subjectPublicKey of the certificate.
+func KeyIdentifier(cert *x509.Certificate) (string, error) {
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(cert.RawSubjectPublicKeyInfo, &spki); err != nil {
+		return "", errors.Wrap(err, "unable to parse subjectPublicKeyInfo")
+	}
+	sum := sha1.Sum(spki.PublicKey.Bytes)
+	return hex.EncodeToString(sum[:]), nil
+}
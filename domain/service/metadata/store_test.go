@@ -0,0 +1,249 @@
+package metadata
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/miliya612/webauthn-demo/domain/service/jws"
+)
+
+var testNow = time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+// signBLOB signs blob with a certificate issued by a new root, which is returned as the roots.
+func signBLOB(t *testing.T, blob BLOB) ([]byte, *x509.CertPool) {
+	t.Helper()
+	root, rootKey := newCertificate(t, nil, nil, true)
+	leaf, leafKey := newCertificate(t, root, rootKey, false)
+
+	header, _ := json.Marshal(jws.Header{Alg: "ES256", X5c: []string{base64.StdEncoding.EncodeToString(leaf.Raw)}})
+	payload, _ := json.Marshal(blob)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, leafKey, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	return []byte(signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)), roots
+}
+
+// newCertificate issues a certificate by parent, or a self-signed one if parent is nil.
+func newCertificate(
+	t *testing.T, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, isCA bool) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "Test Metadata " + serial.String()},
+		NotBefore:             testNow.Add(-time.Hour),
+		NotAfter:              testNow.Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	if isCA {
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert, key
+}
+
+func TestParse(t *testing.T) {
+	token, roots := signBLOB(t, BLOB{No: 3, NextUpdate: "2021-07-01"})
+	blob, err := Parse(token, roots, testNow)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if blob.No != 3 {
+		t.Errorf("got no %v, want 3", blob.No)
+	}
+
+	_, otherRoots := signBLOB(t, BLOB{})
+	if _, err := Parse(token, otherRoots, testNow); err == nil {
+		t.Error("expected an error for a BLOB which does not chain up to roots, but got nil")
+	}
+
+	tests := []struct {
+		name       string
+		nextUpdate string
+		wantErr    bool
+	}{
+		{name: "on nextUpdate", nextUpdate: "2021-06-01"},
+		{name: "past nextUpdate", nextUpdate: "2021-05-31", wantErr: true},
+		{name: "no nextUpdate", nextUpdate: "", wantErr: true},
+		{name: "malformed nextUpdate", nextUpdate: "2021-07-01T00:00:00Z", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, roots := signBLOB(t, BLOB{No: 3, NextUpdate: tt.nextUpdate})
+			if _, err := Parse(token, roots, testNow); (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRefreshExpired(t *testing.T) {
+	dir := t.TempDir()
+	src, cache := filepath.Join(dir, "new.jwt"), filepath.Join(dir, "blob.jwt")
+	token, roots := signBLOB(t, BLOB{No: 3, NextUpdate: "2021-05-31"})
+	if err := ioutil.WriteFile(src, token, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadFile(src, roots, testNow); err == nil {
+		t.Error("LoadFile: expected an error for an expired BLOB, but got nil")
+	}
+	if _, err := Refresh(src, cache, roots, testNow); err == nil {
+		t.Error("Refresh: expected an error for an expired BLOB, but got nil")
+	}
+	if _, err := os.Stat(cache); !os.IsNotExist(err) {
+		t.Errorf("expected the expired BLOB not to be cached, but got %v", err)
+	}
+}
+
+func TestCheckStatus(t *testing.T) {
+	attCert, _ := newCertificate(t, nil, nil, false)
+	otherCert, _ := newCertificate(t, nil, nil, false)
+	keyIdentifier, err := KeyIdentifier(attCert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aaguid := []byte{0xf8, 0xa0, 0x11, 0xf3, 0x8c, 0x0a, 0x4d, 0x15, 0x80, 0x06, 0x17, 0x11, 0x1f, 0x9e, 0xdc, 0x7d}
+	zeroAAGUID := make([]byte, 16)
+
+	tests := []struct {
+		name      string
+		entry     Entry
+		aaguid    []byte
+		trustPath []*x509.Certificate
+		rejected  bool
+	}{
+		{
+			name:   "certified",
+			entry:  Entry{AAGUID: "F8A011F3-8C0A-4D15-8006-17111F9EDC7D", StatusReports: []StatusReport{{Status: StatusFIDOCertified}}},
+			aaguid: aaguid,
+		},
+		{
+			name: "revoked",
+			entry: Entry{AAGUID: "f8a011f3-8c0a-4d15-8006-17111f9edc7d", StatusReports: []StatusReport{
+				{Status: StatusFIDOCertified, EffectiveDate: "2019-01-01"},
+				{Status: StatusRevoked, EffectiveDate: "2020-01-01"},
+			}},
+			aaguid:   aaguid,
+			rejected: true,
+		},
+		{
+			name: "user verification bypass is fixed by an update",
+			entry: Entry{AAGUID: "f8a011f3-8c0a-4d15-8006-17111f9edc7d", StatusReports: []StatusReport{
+				{Status: StatusUpdateAvailable, EffectiveDate: "2020-02-01"},
+				{Status: StatusUserVerificationBypass, EffectiveDate: "2020-01-01"},
+			}},
+			aaguid: aaguid,
+		},
+		{
+			name: "revoked before a later report",
+			entry: Entry{AAGUID: "f8a011f3-8c0a-4d15-8006-17111f9edc7d", StatusReports: []StatusReport{
+				{Status: StatusRevoked, EffectiveDate: "2020-01-01"},
+				{Status: StatusUpdateAvailable, EffectiveDate: "2020-02-01"},
+				{Status: StatusFIDOCertified, EffectiveDate: "2020-03-01"},
+			}},
+			aaguid:   aaguid,
+			rejected: true,
+		},
+		{
+			name: "attestation key compromise before a later report",
+			entry: Entry{AttestationCertificateKeyIdentifiers: []string{keyIdentifier}, StatusReports: []StatusReport{
+				{Status: StatusAttestationKeyCompromise, EffectiveDate: "2020-01-01"},
+				{Status: StatusUpdateAvailable, EffectiveDate: "2020-02-01"},
+			}},
+			aaguid:    zeroAAGUID,
+			trustPath: []*x509.Certificate{attCert},
+			rejected:  true,
+		},
+		{
+			name: "user verification bypass is the latest report",
+			entry: Entry{AAGUID: "f8a011f3-8c0a-4d15-8006-17111f9edc7d", StatusReports: []StatusReport{
+				{Status: StatusFIDOCertified, EffectiveDate: "2019-01-01"},
+				{Status: StatusUserVerificationBypass, EffectiveDate: "2020-01-01"},
+			}},
+			aaguid:   aaguid,
+			rejected: true,
+		},
+		{
+			name: "attestation key compromise by key identifier",
+			entry: Entry{AttestationCertificateKeyIdentifiers: []string{keyIdentifier}, StatusReports: []StatusReport{
+				{Status: StatusAttestationKeyCompromise},
+			}},
+			aaguid:    zeroAAGUID,
+			trustPath: []*x509.Certificate{attCert},
+			rejected:  true,
+		},
+		{
+			name: "attestation key compromise of another certificate",
+			entry: Entry{AttestationCertificateKeyIdentifiers: []string{keyIdentifier}, StatusReports: []StatusReport{
+				{Status: StatusAttestationKeyCompromise, Certificate: base64.StdEncoding.EncodeToString(otherCert.Raw)},
+			}},
+			aaguid:    zeroAAGUID,
+			trustPath: []*x509.Certificate{attCert},
+		},
+		{
+			name: "attestation key compromise of another certificate before a later report",
+			entry: Entry{AttestationCertificateKeyIdentifiers: []string{keyIdentifier}, StatusReports: []StatusReport{
+				{
+					Status:        StatusAttestationKeyCompromise,
+					EffectiveDate: "2020-01-01",
+					Certificate:   base64.StdEncoding.EncodeToString(otherCert.Raw),
+				},
+				{Status: StatusFIDOCertified, EffectiveDate: "2020-02-01"},
+			}},
+			aaguid:    zeroAAGUID,
+			trustPath: []*x509.Certificate{attCert},
+		},
+		{
+			name:   "not listed",
+			entry:  Entry{AAGUID: "00000000-0000-0000-0000-000000000001", StatusReports: []StatusReport{{Status: StatusRevoked}}},
+			aaguid: aaguid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStore(&BLOB{Entries: []Entry{tt.entry}})
+			err := s.CheckStatus(tt.aaguid, tt.trustPath)
+			if tt.rejected && err == nil {
+				t.Error("expected an error, but got nil")
+			}
+			if !tt.rejected && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -7,18 +7,21 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"github.com/miliya612/webauthn-demo/config"
+	"github.com/miliya612/webauthn-demo/domain/errUtil"
 	"github.com/miliya612/webauthn-demo/domain/model"
 	"github.com/miliya612/webauthn-demo/domain/repo"
 	"github.com/miliya612/webauthn-demo/domain/service/attestation"
 	"github.com/miliya612/webauthn-demo/webauthnif"
 	"github.com/pkg/errors"
-	"github.com/ugorji/go/codec"
 )
 
 type RegistrationService interface {
 	GetOptions(id, displayName string) (*webauthnif.CredentialCreationOptions, error)
-	ReserveClientInfo(userId []byte, name, displayName, icon string) error
-	Register(userId []byte, data webauthnif.AuthenticatorData) error
+	ReserveClientInfo(userId []byte, name, displayName, icon string, authenticated bool) error
+	Register(
+		userId []byte, data webauthnif.AuthenticatorData, attType attestation.AttestationType, authenticated bool,
+	) error
 	ParseClientData(req webauthnif.AuthenticatorAttestationResponse) (
 		*webauthnif.CollectedClientData, error)
 	ValidateClientData(rawChal []byte, c webauthnif.CollectedClientData) error
@@ -27,29 +30,36 @@ type RegistrationService interface {
 		d *webauthnif.DecodedAuthenticatorAttestationResponse,
 	) (*webauthnif.DecodedAuthenticatorAttestationResponse, error)
 	ValidateClientExtensionOutputs(outputs webauthnif.AuthenticationExtensionsClientOutputs) error
-	ValidateAttestationResponse(attObj webauthnif.DecodedAttestationObject, hashedClientData [32]byte) error
+	ValidateAttestationResponse(attObj webauthnif.DecodedAttestationObject, hashedClientData [32]byte) (
+		*attestation.Result, error)
 	ValidateAuthenticatorData(data webauthnif.AuthenticatorData) error
 }
 
 type registrationService struct {
 	credentialRepo repo.CredentialRepo
 	userRepo       repo.UserRepo
+	trust          attestation.Trust
+	tenantID       string
+	rp             config.RPConfig
 }
 
+// NewRegistrationService returns a RegistrationService which runs the registration ceremony for the Relying Party
+// of tenant. Users and credentials are registered with the tenant.
 func NewRegistrationService(
-	credential repo.CredentialRepo, user repo.UserRepo, session repo.SessionRepo) RegistrationService {
+	credential repo.CredentialRepo, user repo.UserRepo, session repo.SessionRepo, trust attestation.Trust,
+	tenant config.Tenant,
+) RegistrationService {
 	return &registrationService{
 		credentialRepo: credential,
 		userRepo:       user,
+		trust:          trust,
+		tenantID:       tenant.TenantID,
+		rp:             tenant.RPConfig,
 	}
 }
 
 const (
-	RPID                       string = "localhost"
-	RPNAME                     string = "miliya612 - webauthn demo"
-	TIMEOUTMILLSEC             uint32 = 6000
-	CLIENTDATATYPE             string = "webauthn.create"
-	IsUserVerificationRequired bool   = false
+	CLIENTDATATYPE string = "webauthn.create"
 )
 
 // GetOptions returns CredentialCreationOptions to client. It will be used when calling navigator.credentials.create().
@@ -61,10 +71,10 @@ func (s registrationService) GetOptions(id, displayName string) (*webauthnif.Cre
 	rp := &webauthnif.PublicKeyCredentialRpEntity{
 		// rpidのscopeを指定した場合はここで指定
 		// defaultではsubdomainつきのFQDNとか?
-		ID: RPID,
+		ID: s.rp.ID,
 		PublicKeyCredentialEntity: webauthnif.PublicKeyCredentialEntity{
 			// TODO: これheaderのtitleから取った方が良い?
-			Name: RPNAME,
+			Name: s.rp.Name,
 		},
 	}
 
@@ -82,15 +92,12 @@ func (s registrationService) GetOptions(id, displayName string) (*webauthnif.Cre
 		return nil, err
 	}
 
-	credentialParams := &webauthnif.PublicKeyCredentialParameters{
-		webauthnif.PublicKeyCredentialParameter{
+	credentialParams := webauthnif.PublicKeyCredentialParameters{}
+	for _, alg := range s.rp.Algorithms {
+		credentialParams = append(credentialParams, webauthnif.PublicKeyCredentialParameter{
 			Type: webauthnif.PublicKeyCredentialTypePublicKey,
-			Alg:  webauthnif.COSEAlgorithmIdentifierES256,
-		},
-		webauthnif.PublicKeyCredentialParameter{
-			Type: webauthnif.PublicKeyCredentialTypePublicKey,
-			Alg:  webauthnif.COSEAlgorithmIdentifierRS256,
-		},
+			Alg:  alg,
+		})
 	}
 
 	excludeCredentials := webauthnif.PublicKeyCredentialDescriptors{
@@ -109,11 +116,11 @@ func (s registrationService) GetOptions(id, displayName string) (*webauthnif.Cre
 
 	authenticatorSelection := webauthnif.AuthenticatorSelectionCriteria{
 		AuthenticatorAttachment: webauthnif.AuthenticatorAttachmentEmpty,
-		RequireResidentKey:      false, // default
-		UserVerification:        webauthnif.UserVerificationRequirementEmpty,
+		RequireResidentKey:      s.rp.RequireResidentKey,
+		UserVerification:        s.rp.UserVerification,
 	}
 
-	acp := webauthnif.AttestationConveyancePreferenceDirect
+	acp := s.rp.Attestation
 
 	extensions := webauthnif.AuthenticationExtensionsClientInputs{}
 
@@ -121,8 +128,8 @@ func (s registrationService) GetOptions(id, displayName string) (*webauthnif.Cre
 		RP:                     *rp,
 		User:                   *user,
 		Challenge:              challenge,
-		PubKeyCredParams:       *credentialParams,
-		Timeout:                TIMEOUTMILLSEC,
+		PubKeyCredParams:       credentialParams,
+		Timeout:                s.rp.Timeout,
 		ExcludeCredentials:     excludeCredentials,
 		AuthenticatorSelection: authenticatorSelection,
 		Attestation:            acp,
@@ -136,14 +143,26 @@ func (s registrationService) GetOptions(id, displayName string) (*webauthnif.Cre
 	return options, nil
 }
 
-func (s registrationService) ReserveClientInfo(userId []byte, name, displayName, icon string) error {
+// ReserveClientInfo registers the user account the credential is created for. The user handle of an account which
+// has a credential is refused unless the user has been authenticated, i.e. authenticated is true, since anyone can
+// start a registration ceremony, and the credential would be registered with the account of another user. The account
+// of a ceremony which has not been finished is reserved again.
+func (s registrationService) ReserveClientInfo(userId []byte, name, displayName, icon string, authenticated bool,
+) error {
 	u := &model.User{
+		TenantID:    s.tenantID,
 		ID:          userId,
 		Name:        name,
 		DisplayName: displayName,
 		Icon:        icon,
 	}
 	_, err := s.userRepo.Create(*u)
+	if _, ok := err.(errUtil.ErrUserConflict); ok {
+		if authenticated {
+			return nil
+		}
+		return s.checkUnclaimed(userId)
+	}
 	if err != nil {
 		return err
 	}
@@ -151,6 +170,18 @@ func (s registrationService) ReserveClientInfo(userId []byte, name, displayName,
 	return nil
 }
 
+// checkUnclaimed returns an error if a credential has been registered with the user account.
+func (s registrationService) checkUnclaimed(userId []byte) error {
+	creds, err := s.credentialRepo.GetByUserID(s.tenantID, userId)
+	if err != nil {
+		return err
+	}
+	if len(creds) != 0 {
+		return errors.New(fmt.Sprintf("invalidRegistrationRequest: %v", errUtil.ErrUserConflict{}))
+	}
+	return nil
+}
+
 func (s registrationService) ParseClientData(req webauthnif.AuthenticatorAttestationResponse) (
 	*webauthnif.CollectedClientData, error) {
 
@@ -190,16 +221,26 @@ func (s registrationService) ValidateClientData(rawChal []byte, c webauthnif.Col
 	}
 
 	// 5. Verify that the value of C.origin matches the Relying Party's origin.
-	// TODO: RPID, subdomainマッチのロジック必要そう。subDomainとrootDomainの判定処理も書く
-	if c.Origin != "http://" + RPID + ":8080" {
-		errMsg := "invalid origin"
-		return errors.New(fmt.Sprintf("invalidRegistrationRequest: %v", errMsg))
+	if err := s.rp.CheckOrigin(c.Origin); err != nil {
+		return errors.New(fmt.Sprintf("invalidRegistrationRequest: %v", err))
+	}
+
+	// If C.topOrigin is present:
+	// 1. Verify that the Relying Party expects that this credential would have been created within an iframe that is
+	// not same-origin with its ancestors.
+	// 2. Verify that the value of C.topOrigin matches the origin of a page that the Relying Party expects to be
+	// sub-framed within.
+	// C.crossOrigin is checked in the same way, since clients of WebAuthn Level 2 report it without C.topOrigin.
+	if err := s.rp.CheckCrossOrigin(c.CrossOrigin, c.TopOrigin); err != nil {
+		return errors.New(fmt.Sprintf("invalidRegistrationRequest: %v", err))
 	}
 
 	// 6. Verify that the value of C.tokenBinding.status matches the state of Token Binding for the TLS connection over
 	// which the assertion was obtained. If Token Binding was used on that TLS connection, also verify that
 	// C.tokenBinding.id matches the base64url encoding of the Token Binding ID for the connection.
-	// TODO: やる
+	if err := verifyTokenBinding(c.TokenBinding); err != nil {
+		return errors.New(fmt.Sprintf("invalidRegistrationRequest: %v", err))
+	}
 
 	return nil
 }
@@ -211,25 +252,18 @@ func (s registrationService) ParseAttestationObj(
 	// 8. Perform CBOR decoding on the attestationObject field of the AuthenticatorAttestationResponse structure to
 	// obtain the attestation statement format fmt, the authenticator data authData, and the attestation statement
 	// attStmt.
-	handle := codec.CborHandle{}
-	err := codec.NewDecoder(bytes.NewReader(req), &handle).Decode(&d.DecodedAttestationObject)
-	if err != nil {
-		return nil, err
-	}
-	fmt.Println("-----")
-	fmt.Println(d.DecodedAttestationObject.RawAuthData)
-
-	err = d.DecodedAttestationObject.UnmarshalBinary()
+	attObj, err := webauthnif.ParseAttestationObject(req)
 	if err != nil {
 		return nil, err
 	}
+	d.DecodedAttestationObject = *attObj
 
 	return d, nil
 }
 
 func (s registrationService) ValidateAuthenticatorData(data webauthnif.AuthenticatorData) error {
 	// 9. Verify that the RP ID hash in authData is indeed the SHA-256 hash of the RP ID expected by the RP.
-	wantRpIdHash := sha256.Sum256([]byte(RPID))
+	wantRpIdHash := sha256.Sum256([]byte(s.rp.ID))
 	gotRpIdHash := data.RPIDHash
 	if !bytes.Equal(wantRpIdHash[:], gotRpIdHash) {
 		errMsg := "invalid origin"
@@ -244,12 +278,36 @@ func (s registrationService) ValidateAuthenticatorData(data webauthnif.Authentic
 
 	// 11. If user verification is required for this registration, verify that the User Verified bit of the flags in
 	// authData is set.
-	if IsUserVerificationRequired {
+	if s.rp.UserVerificationRequired() {
 		if !data.Flags.UserVerified() {
 			errMsg := "no user verification"
 			return errors.New(fmt.Sprintf("invalidRegistrationRequest: %v", errMsg))
 		}
 	}
+
+	// If the BE bit of the flags in authData is not set, verify that the BS bit is not set.
+	if !data.Flags.BackupEligible() && data.Flags.BackedUp() {
+		errMsg := "backup state is set on a credential which is not backup eligible"
+		return errors.New(fmt.Sprintf("invalidRegistrationRequest: %v", errMsg))
+	}
+
+	// Verify that the "alg" parameter in the credential public key in authData matches the alg attribute of one of the
+	// items in options.pubKeyCredParams.
+	key := data.AttestedCredentialData.DecodedCredentialPublicKey
+	if key == nil {
+		errMsg := "no credential public key"
+		return errors.New(fmt.Sprintf("invalidRegistrationRequest: %v", errMsg))
+	}
+	offered := false
+	for _, alg := range s.rp.Algorithms {
+		if webauthnif.COSEAlgorithmIdentifier(key.Algorithm()) == alg {
+			offered = true
+		}
+	}
+	if !offered {
+		errMsg := fmt.Sprintf("algorithm %d is not in pubKeyCredParams", key.Algorithm())
+		return errors.New(fmt.Sprintf("invalidRegistrationRequest: %v", errMsg))
+	}
 	return nil
 }
 
@@ -265,25 +323,25 @@ func (s registrationService) ValidateClientExtensionOutputs(
 }
 
 func (s registrationService) ValidateAttestationResponse(
-	attObj webauthnif.DecodedAttestationObject, hashedClientData [32]byte) error {
+	attObj webauthnif.DecodedAttestationObject, hashedClientData [32]byte) (*attestation.Result, error) {
 	// 13. Determine the attestation statement format by performing a USASCII case-sensitive match on fmt against the
 	// set of supported WebAuthn Attestation Statement Format Identifier values. The up-to-date list of registered
 	// WebAuthn Attestation Statement Format Identifier values is maintained in the in the IANA registry of the same
 	// name.
 
-	verifier, ok := attestation.AttVerifiers[attObj.Fmt]
+	verifier, ok := s.trust.Verifier(attObj.Fmt)
 	if !ok {
 		errMsg := "Unsupported attestation statement format identifier"
-		return errors.New(fmt.Sprintf("invalidRegistrationRequest: %v", errMsg))
+		return nil, errors.New(fmt.Sprintf("invalidRegistrationRequest: %v", errMsg))
 	}
 
 	// 14. Verify that attStmt is a correct attestation statement, conveying a valid attestation signature, by using the
 	// attestation statement format fmt’s verification procedure given attStmt, authData and the hash of the serialized
 	// client data computed in step 7.
-	err := verifier(attObj, hashedClientData)
+	result, err := verifier(attObj, hashedClientData)
 	if err != nil {
-		errMsg := fmt.Sprintf("attestation statement is not matched with its format: %v", attObj.Fmt)
-		return errors.New(fmt.Sprintf("invalidRegistrationRequest: %v", errMsg))
+		errMsg := fmt.Sprintf("attestation statement is not matched with its format: %v: %v", attObj.Fmt, err)
+		return nil, errors.New(fmt.Sprintf("invalidRegistrationRequest: %v", errMsg))
 	}
 
 	// 15. If validation is successful, obtain a list of acceptable trust anchors (attestation root certificates or
@@ -297,15 +355,23 @@ func (s registrationService) ValidateAttestationResponse(
 	//     acceptable trust anchors obtained in step 15.
 	//     - Otherwise, use the X.509 certificates returned by the verification procedure to verify that the attestation
 	//     public key correctly chains up to an acceptable root certificate.
+	aaguid := attObj.AuthData.AttestedCredentialData.AAGUID
+	result, err = s.trust.Assess(attObj.Fmt, aaguid, *result)
+	if err != nil {
+		errMsg := fmt.Sprintf("attestation is not trustworthy: %v", err)
+		return nil, errors.New(fmt.Sprintf("invalidRegistrationRequest: %v", errMsg))
+	}
 
-	return nil
+	return result, nil
 }
 
-func (s registrationService) Register(userId []byte, data webauthnif.AuthenticatorData) error {
+func (s registrationService) Register(
+	userId []byte, data webauthnif.AuthenticatorData, attType attestation.AttestationType, authenticated bool,
+) error {
 	// 17. Check that the credentialId is not yet registered to any other user. If registration is requested for a
 	// credential that is already registered to a different user, the Relying Party SHOULD fail this registration
 	// ceremony, or it MAY decide to accept the registration, e.g. while deleting the older registration.
-	cred, err := s.credentialRepo.GetByCredentialID(data.AttestedCredentialData.CredentialID)
+	cred, err := s.credentialRepo.GetByCredentialID(s.tenantID, data.AttestedCredentialData.CredentialID)
 	if err != nil {
 		return errors.New(fmt.Sprintf("invalidRegistrationRequest: %v", err))
 	}
@@ -313,16 +379,25 @@ func (s registrationService) Register(userId []byte, data webauthnif.Authenticat
 		errMsg := "credentialId has already been registered"
 		return errors.New(fmt.Sprintf("invalidRegistrationRequest: %v", errMsg))
 	}
+	// Another ceremony for the same account may have been finished since this one started.
+	if !authenticated {
+		if err := s.checkUnclaimed(userId); err != nil {
+			return err
+		}
+	}
 
 	// 18. If the attestation statement attStmt verified successfully and is found to be trustworthy, then register the
 	// new credential with the account that was denoted in the options.user passed to create(), by associating it with
 	// the credentialId and credentialPublicKey in the attestedCredentialData in authData, as appropriate for the
 	// Relying Party's system.
 	newCred := model.Credential{
+		TenantID:     s.tenantID,
 		CredentialID: data.AttestedCredentialData.CredentialID,
 		UserID:       userId,
 		PublicKey:    data.AttestedCredentialData.CredentialPublicKey,
 		SignCount:    data.SignCount,
+		// The attestation type is kept so that the Relying Party can re-evaluate the credential under its policy.
+		AttestationType: string(attType),
 	}
 	_, err = s.credentialRepo.Create(newCred)
 	if err != nil {
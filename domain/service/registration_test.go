@@ -1,7 +1,60 @@
 package service
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/miliya612/webauthn-demo/config"
+	"github.com/miliya612/webauthn-demo/domain/service/attestation"
+	"github.com/miliya612/webauthn-demo/infra/persistance/memory"
+	"github.com/miliya612/webauthn-demo/webauthnif"
+)
 
 func TestIsValidAttestationResponse(t *testing.T) {
 
 }
+
+func TestReserveClientInfo(t *testing.T) {
+	db := memory.NewDB()
+	tenant := config.Tenant{TenantID: config.DefaultTenantID, RPConfig: config.Default()}
+	s := NewRegistrationService(
+		memory.NewCredentialRepo(db), memory.NewUserRepo(db), memory.NewSessionRepo(db), attestation.Trust{}, tenant)
+	alice := []byte("alice")
+	data := webauthnif.AuthenticatorData{
+		AttestedCredentialData: webauthnif.AttestedCredentialData{CredentialID: []byte{1}},
+	}
+
+	if err := s.ReserveClientInfo(alice, "alice", "Alice", "", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The ceremony has not been finished, so the account is reserved again.
+	if err := s.ReserveClientInfo(alice, "alice", "Alice", "", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Register(alice, data, attestation.AttestationTypeNone, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := s.ReserveClientInfo(alice, "alice", "Mallory", "", false); err == nil {
+		t.Error("expected an error for the user handle of a registered account, but got nil")
+	}
+	u, err := memory.NewUserRepo(db).GetByID(config.DefaultTenantID, alice)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u.DisplayName != "Alice" {
+		t.Errorf("got the display name %q, want %q", u.DisplayName, "Alice")
+	}
+	// A ceremony started before the account has been claimed does not register another credential with it.
+	data.AttestedCredentialData.CredentialID = []byte{2}
+	if err := s.Register(alice, data, attestation.AttestationTypeNone, false); err == nil {
+		t.Error("expected an error for a credential of a registered account, but got nil")
+	}
+
+	// The user registers another credential after authenticating.
+	if err := s.ReserveClientInfo(alice, "alice", "Alice", "", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Register(alice, data, attestation.AttestationTypeNone, true); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -8,33 +8,42 @@ import (
 
 type SessionService interface {
 	Get(sid string) (*model.Session, bool)
-	Store(sid string, uid, chal []byte) error
+	Store(sid string, uid, chal []byte, authenticated bool) error
+	Authenticate(sid string, uid []byte) error
+	Delete(sid string) error
 }
 
 type sessionService struct {
-	repo repo.SessionRepo
+	repo     repo.SessionRepo
+	tenantID string
 }
 
-func NewSessionService(session repo.SessionRepo) SessionService {
+// NewSessionService returns a SessionService which stores the sessions of the tenant identified by tenantID.
+func NewSessionService(session repo.SessionRepo, tenantID string) SessionService {
 	return &sessionService{
-		repo: session,
+		repo:     session,
+		tenantID: tenantID,
 	}
 }
 
 func (s *sessionService) Get(sid string) (*model.Session, bool) {
-	session, err := s.repo.GetByID(sid)
+	session, err := s.repo.GetByID(s.tenantID, sid)
 	if err != nil {
 		return nil, false
 	}
 	return session, true
 }
 
-func (s *sessionService) Store(sid string, uid, chal []byte) error {
+// Store stores the session of a ceremony with the challenge. authenticated is whether the user had been authenticated
+// before the ceremony started.
+func (s *sessionService) Store(sid string, uid, chal []byte, authenticated bool) error {
 	session := &model.Session{
-		ID:           sid,
-		UserID:       uid,
-		Challenge:    chal,
-		LastAccessed: time.Now(),
+		TenantID:      s.tenantID,
+		ID:            sid,
+		UserID:        uid,
+		Challenge:     chal,
+		LastAccessed:  time.Now(),
+		Authenticated: authenticated,
 	}
 	_, err := s.repo.Create(*session)
 	if err != nil {
@@ -42,3 +51,14 @@ func (s *sessionService) Store(sid string, uid, chal []byte) error {
 	}
 	return nil
 }
+
+// Authenticate stores the session of the user authenticated by a ceremony, which has no challenge since no ceremony is
+// running in it.
+func (s *sessionService) Authenticate(sid string, uid []byte) error {
+	return s.Store(sid, uid, []byte{}, true)
+}
+
+func (s *sessionService) Delete(sid string) error {
+	_, err := s.repo.Delete(s.tenantID, sid)
+	return err
+}
@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"bytes"
+	"github.com/miliya612/webauthn-demo/domain/errUtil"
+	"github.com/miliya612/webauthn-demo/domain/model"
+	"github.com/miliya612/webauthn-demo/domain/repo"
+	"github.com/pkg/errors"
+	"sort"
+)
+
+type credentialRepo struct {
+	db *DB
+}
+
+// credentialRecord is a credential stored in DB, which is keyed by its credential ID since credential IDs are unique
+// across tenants.
+type credentialRecord model.Credential
+
+func NewCredentialRepo(db *DB) repo.CredentialRepo {
+	return credentialRepo{db: db}
+}
+
+// GetByCredentialID returns nil without an error if the credential is not registered.
+func (repo credentialRepo) GetByCredentialID(tenantID string, id []byte) (*model.Credential, error) {
+	repo.db.mu.RLock()
+	defer repo.db.mu.RUnlock()
+	c, ok := repo.db.credentials[string(id)]
+	if !ok || c.TenantID != tenantID {
+		return nil, nil
+	}
+	return c.credential(), nil
+}
+
+func (repo credentialRepo) GetByUserID(tenantID string, userId []byte) ([]model.Credential, error) {
+	repo.db.mu.RLock()
+	defer repo.db.mu.RUnlock()
+	var creds []model.Credential
+	for _, c := range repo.db.credentials {
+		if c.TenantID == tenantID && bytes.Equal(c.UserID, userId) {
+			creds = append(creds, *c.credential())
+		}
+	}
+	sort.Slice(creds, func(i, j int) bool { return bytes.Compare(creds[i].CredentialID, creds[j].CredentialID) < 0 })
+	return creds, nil
+}
+
+func (repo credentialRepo) Create(credential model.Credential) (*model.Credential, error) {
+	repo.db.mu.Lock()
+	defer repo.db.mu.Unlock()
+	if _, ok := repo.db.credentials[string(credential.CredentialID)]; ok {
+		return nil, errors.New("credential ID has already been registered")
+	}
+	if _, ok := repo.db.users[key{credential.TenantID, string(credential.UserID)}]; !ok {
+		return nil, errUtil.ErrUserNotFound{}
+	}
+	repo.db.credentials[string(credential.CredentialID)] = newCredentialRecord(credential)
+	return newCredentialRecord(credential).credential(), nil
+}
+
+// Update updates the public key, the signature counter and the attestation type of the credential.
+func (repo credentialRepo) Update(credential model.Credential) (*model.Credential, error) {
+	repo.db.mu.Lock()
+	defer repo.db.mu.Unlock()
+	c, ok := repo.db.credentials[string(credential.CredentialID)]
+	if !ok || c.TenantID != credential.TenantID {
+		return nil, errUtil.ErrCredentialNotFound{}
+	}
+	c.PublicKey = copyBytes(credential.PublicKey)
+	c.SignCount = credential.SignCount
+	c.AttestationType = credential.AttestationType
+	return c.credential(), nil
+}
+
+func (repo credentialRepo) Delete(tenantID string, id []byte) ([]byte, error) {
+	repo.db.mu.Lock()
+	defer repo.db.mu.Unlock()
+	c, ok := repo.db.credentials[string(id)]
+	if !ok || c.TenantID != tenantID {
+		return nil, errUtil.ErrCredentialNotFound{}
+	}
+	delete(repo.db.credentials, string(id))
+	return copyBytes(id), nil
+}
+
+func (repo credentialRepo) UpdateSignCount(tenantID string, id []byte, signCount uint32) (bool, error) {
+	repo.db.mu.Lock()
+	defer repo.db.mu.Unlock()
+	c, ok := repo.db.credentials[string(id)]
+	if !ok || c.TenantID != tenantID || signCount <= c.SignCount {
+		return false, nil
+	}
+	c.SignCount = signCount
+	return true, nil
+}
+
+func newCredentialRecord(c model.Credential) *credentialRecord {
+	r := credentialRecord(c)
+	r.CredentialID = copyBytes(c.CredentialID)
+	r.UserID = copyBytes(c.UserID)
+	r.PublicKey = copyBytes(c.PublicKey)
+	return &r
+}
+
+// credential returns a copy of the stored credential.
+func (r *credentialRecord) credential() *model.Credential {
+	c := model.Credential(*newCredentialRecord(model.Credential(*r)))
+	return &c
+}
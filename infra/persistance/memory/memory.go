@@ -0,0 +1,38 @@
+package memory
+
+import (
+	"sync"
+)
+
+// DB is the storage of the repositories, which plays the role of the database in the other backends. Users,
+// credentials and sessions are lost on restart. It is safe for concurrent use, and the repositories copy the values
+// they store and return, so that callers never share them.
+type DB struct {
+	mu          sync.RWMutex
+	users       map[key]*userRecord
+	credentials map[string]*credentialRecord
+	sessions    map[key]*sessionRecord
+}
+
+// NewDB returns an empty DB.
+func NewDB() *DB {
+	return &DB{
+		users:       map[key]*userRecord{},
+		credentials: map[string]*credentialRecord{},
+		sessions:    map[key]*sessionRecord{},
+	}
+}
+
+// key identifies a user or a session within its tenant.
+type key struct {
+	tenantID string
+	id       string
+}
+
+// copyBytes returns a copy of b, which is nil if b is nil.
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/miliya612/webauthn-demo/infra/persistance/repotest"
+)
+
+func TestRepos(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repos {
+		db := NewDB()
+		return repotest.Repos{Users: NewUserRepo(db), Credentials: NewCredentialRepo(db), Sessions: NewSessionRepo(db)}
+	})
+}
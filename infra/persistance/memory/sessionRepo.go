@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"github.com/miliya612/webauthn-demo/domain/errUtil"
+	"github.com/miliya612/webauthn-demo/domain/model"
+	"github.com/miliya612/webauthn-demo/domain/repo"
+	"github.com/pkg/errors"
+	"time"
+)
+
+type sessionRepo struct {
+	db *DB
+}
+
+// sessionRecord is a session stored in DB.
+type sessionRecord model.Session
+
+func NewSessionRepo(db *DB) repo.SessionRepo {
+	return sessionRepo{db: db}
+}
+
+// GetByID returns the session, and records that it is accessed now.
+func (repo sessionRepo) GetByID(tenantID string, id string) (*model.Session, error) {
+	repo.db.mu.Lock()
+	defer repo.db.mu.Unlock()
+	s, ok := repo.db.sessions[key{tenantID, id}]
+	if !ok {
+		return nil, errUtil.ErrSessionNotFound{}
+	}
+	s.LastAccessed = time.Now()
+	return s.session(), nil
+}
+
+func (repo sessionRepo) Create(session model.Session) (*model.Session, error) {
+	repo.db.mu.Lock()
+	defer repo.db.mu.Unlock()
+	k := key{session.TenantID, session.ID}
+	if _, ok := repo.db.sessions[k]; ok {
+		return nil, errors.New("session ID is duplicated")
+	}
+	if _, ok := repo.db.users[key{session.TenantID, string(session.UserID)}]; !ok {
+		return nil, errUtil.ErrUserNotFound{}
+	}
+	repo.db.sessions[k] = newSessionRecord(session)
+	return newSessionRecord(session).session(), nil
+}
+
+func (repo sessionRepo) Delete(tenantID string, id string) (int, error) {
+	repo.db.mu.Lock()
+	defer repo.db.mu.Unlock()
+	k := key{tenantID, id}
+	if _, ok := repo.db.sessions[k]; !ok {
+		return 0, errUtil.ErrSessionNotFound{}
+	}
+	delete(repo.db.sessions, k)
+	return 1, nil
+}
+
+func newSessionRecord(s model.Session) *sessionRecord {
+	r := sessionRecord(s)
+	r.UserID = copyBytes(s.UserID)
+	r.Challenge = copyBytes(s.Challenge)
+	return &r
+}
+
+// session returns a copy of the stored session.
+func (r *sessionRecord) session() *model.Session {
+	s := model.Session(*newSessionRecord(model.Session(*r)))
+	return &s
+}
@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"github.com/miliya612/webauthn-demo/domain/errUtil"
+	"github.com/miliya612/webauthn-demo/domain/model"
+	"github.com/miliya612/webauthn-demo/domain/repo"
+)
+
+type userRepo struct {
+	db *DB
+}
+
+// userRecord is a user stored in DB.
+type userRecord model.User
+
+func NewUserRepo(db *DB) repo.UserRepo {
+	return userRepo{db: db}
+}
+
+func (repo userRepo) GetByID(tenantID string, id []byte) (*model.User, error) {
+	repo.db.mu.RLock()
+	defer repo.db.mu.RUnlock()
+	u, ok := repo.db.users[key{tenantID, string(id)}]
+	if !ok {
+		return nil, errUtil.ErrUserNotFound{}
+	}
+	return u.user(), nil
+}
+
+// Create registers the user account. It returns errUtil.ErrUserConflict if the user handle has been registered with
+// the tenant, so that the account of another user is never overwritten.
+func (repo userRepo) Create(user model.User) (*model.User, error) {
+	repo.db.mu.Lock()
+	defer repo.db.mu.Unlock()
+	k := key{user.TenantID, string(user.ID)}
+	if _, ok := repo.db.users[k]; ok {
+		return nil, errUtil.ErrUserConflict{}
+	}
+	repo.db.users[k] = newUserRecord(user)
+	return newUserRecord(user).user(), nil
+}
+
+func (repo userRepo) Update(user model.User) (*model.User, error) {
+	repo.db.mu.Lock()
+	defer repo.db.mu.Unlock()
+	k := key{user.TenantID, string(user.ID)}
+	if _, ok := repo.db.users[k]; !ok {
+		return nil, errUtil.ErrUserNotFound{}
+	}
+	repo.db.users[k] = newUserRecord(user)
+	return newUserRecord(user).user(), nil
+}
+
+func newUserRecord(u model.User) *userRecord {
+	u.ID = copyBytes(u.ID)
+	r := userRecord(u)
+	return &r
+}
+
+// user returns a copy of the stored user.
+func (r *userRecord) user() *model.User {
+	u := model.User(*r)
+	u.ID = copyBytes(u.ID)
+	return &u
+}
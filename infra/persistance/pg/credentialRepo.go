@@ -1,9 +1,9 @@
 package pg
 
 import (
-	"bytes"
 	"database/sql"
 	_ "github.com/lib/pq"
+	"github.com/miliya612/webauthn-demo/domain/errUtil"
 	"github.com/miliya612/webauthn-demo/domain/model"
 	"github.com/miliya612/webauthn-demo/domain/repo"
 )
@@ -12,82 +12,110 @@ type credentialRepo struct {
 	db *sql.DB
 }
 
-var credentials []*model.Credential
-
 func NewCredentialRepo(db *sql.DB) repo.CredentialRepo {
 	return credentialRepo{db: db}
 }
 
-func (repo credentialRepo) GetByCredentialID(id []byte) (*model.Credential, error) {
-	for _, c := range credentials {
-		if bytes.Equal(id, c.CredentialID) {
-			c.SignCount ++
-			return c, nil
+const credentialColumns = "tenant_id, credential_id, user_id, public_key, sign_count, attestation_type"
+
+// GetByCredentialID returns nil without an error if the credential is not registered.
+func (repo credentialRepo) GetByCredentialID(tenantID string, id []byte) (*model.Credential, error) {
+	c, err := scanCredential(repo.db.QueryRow(
+		"select "+credentialColumns+" from credentials where tenant_id = $1 and credential_id = $2", tenantID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (repo credentialRepo) GetByUserID(tenantID string, userId []byte) ([]model.Credential, error) {
+	rows, err := repo.db.Query(
+		"select "+credentialColumns+" from credentials where tenant_id = $1 and user_id = $2 order by credential_id",
+		tenantID, userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var creds []model.Credential
+	for rows.Next() {
+		c, err := scanCredential(rows)
+		if err != nil {
+			return nil, err
 		}
+		creds = append(creds, *c)
 	}
-	//return nil, errors.New("credential not found")
-	return nil, nil
+	return creds, rows.Err()
 }
+
 func (repo credentialRepo) Create(credential model.Credential) (*model.Credential, error) {
-	credentials = append(credentials, &credential)
+	_, err := repo.db.Exec(
+		"insert into credentials ("+credentialColumns+") values ($1, $2, $3, $4, $5, $6)",
+		credential.TenantID, credential.CredentialID, credential.UserID, credential.PublicKey,
+		int64(credential.SignCount), credential.AttestationType,
+	)
+	if err != nil {
+		return nil, err
+	}
 	return &credential, nil
 }
+
+// Update updates the public key, the signature counter and the attestation type of the credential.
 func (repo credentialRepo) Update(credential model.Credential) (*model.Credential, error) {
-	return nil, nil
+	result, err := repo.db.Exec(
+		`update credentials set public_key = $3, sign_count = $4, attestation_type = $5
+		where tenant_id = $1 and credential_id = $2`,
+		credential.TenantID, credential.CredentialID, credential.PublicKey, int64(credential.SignCount),
+		credential.AttestationType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := affected(result); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errUtil.ErrCredentialNotFound{}
+	}
+	return &credential, nil
 }
-func (repo credentialRepo) Delete(id []byte) ([]byte, error) {
-	return nil, nil
+
+func (repo credentialRepo) Delete(tenantID string, id []byte) ([]byte, error) {
+	result, err := repo.db.Exec("delete from credentials where tenant_id = $1 and credential_id = $2", tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := affected(result); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errUtil.ErrCredentialNotFound{}
+	}
+	return id, nil
 }
 
-//
-//
-//func (repo credentialRepo) GetAll() (todos model.Todos, err error) {
-//	rows, err := repo.db.Query("select id, name, completed, due from todos")
-//	if err != nil {
-//		return
-//	}
-//	defer rows.Close()
-//	for rows.Next() {
-//		todo := model.Todo{}
-//		err = rows.Scan(&todo.ID, &todo.Name, &todo.Completed, &todo.Due)
-//		if err != nil {
-//			return
-//		}
-//		todos = append(todos, todo)
-//	}
-//	return
-//}
-//
-//func (repo credentialRepo) GetByID(id int) (todo model.Todo, err error) {
-//	todo = model.Todo{}
-//	err = repo.db.QueryRow("select id, name, completed, due from todos where id = $1", id).Scan(&todo.ID, &todo.Name, &todo.Completed, &todo.Due)
-//	if err == sql.ErrNoRows {
-//		err = errUtil.ErrTodoNotFound{}
-//	}
-//	return
-//}
-//
-//func (repo credentialRepo) Create(todo model.Todo) (int, error) {
-//	stmt, err := repo.db.Prepare("insert into todos (name, due) VALUES ($1, $2) returning id")
-//	if err != nil {
-//		return -1, err
-//	}
-//	defer stmt.Close()
-//	err = stmt.QueryRow(todo.Name, todo.Due).Scan(&todo.ID)
-//	id := todo.ID
-//	return id, err
-//}
-//
-//func (repo credentialRepo) Update(todo model.Todo) (model.Todo, error) {
-//	_, err := repo.db.Exec("update todos set name = $2, completed = $3, due = $4 where id = $1", todo.ID, todo.Name, todo.Completed, todo.Due)
-//	return todo, err
-//}
-//
-//func (repo credentialRepo) Remove(id int) (int, error) {
-//	result, err := repo.db.Exec("delete from todos where id = $1", id)
-//	count, err := result.RowsAffected()
-//	if count == 0 {
-//		err = errUtil.ErrTodoNotFound{}
-//	}
-//	return id, err
-//}
+// UpdateSignCount updates the signature counter in a single statement, whose condition is evaluated against the row
+// locked by the update.
+func (repo credentialRepo) UpdateSignCount(tenantID string, id []byte, signCount uint32) (bool, error) {
+	result, err := repo.db.Exec(
+		"update credentials set sign_count = $3 where tenant_id = $1 and credential_id = $2 and sign_count < $3",
+		tenantID, id, int64(signCount),
+	)
+	if err != nil {
+		return false, err
+	}
+	return affected(result)
+}
+
+func scanCredential(row scanner) (*model.Credential, error) {
+	c := model.Credential{}
+	var signCount int64
+	if err := row.Scan(
+		&c.TenantID, &c.CredentialID, &c.UserID, &c.PublicKey, &signCount, &c.AttestationType); err != nil {
+		return nil, err
+	}
+	c.SignCount = uint32(signCount)
+	return &c, nil
+}
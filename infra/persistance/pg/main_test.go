@@ -0,0 +1,95 @@
+package pg
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// testDatabaseURL is the connection string of the test database. It is EnvTestDatabaseURL if set, or a temporary
+// server started by TestMain otherwise. It is empty if PostgreSQL is not installed either.
+var testDatabaseURL string
+
+// errNoPostgres is returned by startPostgres if the PostgreSQL server binaries are not found.
+var errNoPostgres = errors.New("PostgreSQL is not installed")
+
+func TestMain(m *testing.M) {
+	os.Exit(runTests(m))
+}
+
+func runTests(m *testing.M) int {
+	testDatabaseURL = os.Getenv(EnvTestDatabaseURL)
+	if testDatabaseURL == "" {
+		url, stop, err := startPostgres()
+		switch {
+		case err == errNoPostgres:
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "unable to start PostgreSQL: %v\n", err)
+			return 1
+		default:
+			defer stop()
+			testDatabaseURL = url
+		}
+	}
+	return m.Run()
+}
+
+// startPostgres initializes a database cluster in a temporary directory and starts a server on it, which only listens
+// on a unix domain socket in the directory. The returned function stops the server and removes the directory.
+func startPostgres() (string, func(), error) {
+	bin, err := postgresBinDir()
+	if err != nil {
+		return "", nil, err
+	}
+	dir, err := ioutil.TempDir("", "webauthn-pg")
+	if err != nil {
+		return "", nil, err
+	}
+	data := filepath.Join(dir, "data")
+	initdb := exec.Command(filepath.Join(bin, "initdb"), "-D", data, "-U", "postgres", "-A", "trust", "-N")
+	if out, err := initdb.CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, errors.Wrap(err, string(out))
+	}
+
+	pgCtl := filepath.Join(bin, "pg_ctl")
+	opts := fmt.Sprintf("-k %v -c listen_addresses='' -c fsync=off", dir)
+	start := exec.Command(pgCtl, "-D", data, "-o", opts, "-l", filepath.Join(dir, "postgres.log"), "-w", "start")
+	if out, err := start.CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, errors.Wrap(err, string(out))
+	}
+	stop := func() {
+		if out, err := exec.Command(pgCtl, "-D", data, "-m", "immediate", "-w", "stop").CombinedOutput(); err != nil {
+			fmt.Fprintf(os.Stderr, "unable to stop PostgreSQL: %v: %s\n", err, out)
+		}
+		os.RemoveAll(dir)
+	}
+	return fmt.Sprintf("host=%v user=postgres dbname=postgres sslmode=disable", dir), stop, nil
+}
+
+// postgresBinDir returns the directory of initdb and pg_ctl. Some distributions install them outside of PATH, in
+// which case the directory is asked to pg_config, or looked up in the directory of each installed version.
+func postgresBinDir() (string, error) {
+	if path, err := exec.LookPath("initdb"); err == nil {
+		return filepath.Dir(path), nil
+	}
+	if out, err := exec.Command("pg_config", "--bindir").Output(); err == nil {
+		bin := strings.TrimSpace(string(out))
+		if _, err := os.Stat(filepath.Join(bin, "initdb")); err == nil {
+			return bin, nil
+		}
+	}
+	paths, _ := filepath.Glob("/usr/lib/postgresql/*/bin/initdb")
+	if len(paths) == 0 {
+		return "", errNoPostgres
+	}
+	// The latest version is listed last.
+	return filepath.Dir(paths[len(paths)-1]), nil
+}
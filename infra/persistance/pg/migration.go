@@ -0,0 +1,240 @@
+package pg
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"github.com/pkg/errors"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// migrationFiles are the SQL files of the migrations, which are named "<version>_<name>.up.sql" and
+// "<version>_<name>.down.sql", e.g. "0001_create_users.up.sql". Versions start at 1 without gaps.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// migrationLock is the key of the advisory lock held while a migration is applied or reverted, so that concurrent
+// "migrate" commands apply each migration only once.
+const migrationLock = 0x77656261
+
+// Migration is a version of the schema.
+type Migration struct {
+	Version int
+	Name    string
+	// Up migrates the schema from the previous version to the version.
+	Up string
+	// Down reverts Up.
+	Down string
+}
+
+// MigrationStatus is whether a migration has been applied to the database.
+type MigrationStatus struct {
+	Migration
+	// AppliedAt is zero if the migration has not been applied.
+	AppliedAt time.Time
+}
+
+// Migrations returns the migrations in the order of versions.
+func Migrations() ([]Migration, error) {
+	files, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, f := range files {
+		name := f[len("migrations/"):]
+		m := migrationFileName.FindStringSubmatch(name)
+		if m == nil {
+			return nil, errors.New(fmt.Sprintf("invalid migration file name: %v", name))
+		}
+		version, _ := strconv.Atoi(m[1])
+		b, err := migrationFiles.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != m[2] {
+			return nil, errors.New(fmt.Sprintf("migration %d has two names: %v and %v", version, migration.Name, m[2]))
+		}
+		if m[3] == "up" {
+			migration.Up = string(b)
+		} else {
+			migration.Down = string(b)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, errors.New(fmt.Sprintf("migration %d is missing up or down SQL", m.Version))
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, errors.New(fmt.Sprintf("migration %d is missing", i+1))
+		}
+	}
+	return migrations, nil
+}
+
+// MigrateUp applies the migrations which have not been applied, and returns them.
+func MigrateUp(db *sql.DB) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	var applied []Migration
+	for _, m := range migrations {
+		ok, err := migrate(db, m, true)
+		if err != nil {
+			return applied, errors.Wrap(err, fmt.Sprintf("unable to apply migration %d_%v", m.Version, m.Name))
+		}
+		if ok {
+			applied = append(applied, m)
+		}
+	}
+	return applied, nil
+}
+
+// MigrateDown reverts the last migration applied, and returns it. It returns nil if no migration has been applied.
+func MigrateDown(db *sql.DB) (*Migration, error) {
+	statuses, err := Status(db)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(statuses) - 1; i >= 0; i-- {
+		m := statuses[i].Migration
+		if statuses[i].AppliedAt.IsZero() {
+			continue
+		}
+		if _, err := migrate(db, m, false); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("unable to revert migration %d_%v", m.Version, m.Name))
+		}
+		return &m, nil
+	}
+	return nil, nil
+}
+
+// Status returns whether each migration has been applied to the database.
+func Status(db *sql.DB) ([]MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		statuses[i] = MigrationStatus{Migration: m, AppliedAt: applied[m.Version]}
+		delete(applied, m.Version)
+	}
+	for version := range applied {
+		return nil, errors.New(fmt.Sprintf("migration %d is applied to the database, but unknown to the server", version))
+	}
+	return statuses, nil
+}
+
+// CheckSchema returns an error unless every migration has been applied to the database, i.e. the repositories can
+// run against it.
+func CheckSchema(db *sql.DB) error {
+	statuses, err := Status(db)
+	if err != nil {
+		return err
+	}
+	for _, s := range statuses {
+		if s.AppliedAt.IsZero() {
+			return errors.New(fmt.Sprintf("schema is out of date: migration %d_%v is not applied", s.Version, s.Name))
+		}
+	}
+	return nil
+}
+
+// createMigrationTable creates the table the versions of the migrations applied are recorded in.
+func createMigrationTable(db *sql.DB) error {
+	_, err := db.Exec(`create table if not exists schema_migrations (
+		version    integer     primary key,
+		name       text        not null,
+		applied_at timestamptz not null default now()
+	)`)
+	return err
+}
+
+func appliedVersions(db *sql.DB) (map[int]time.Time, error) {
+	if err := createMigrationTable(db); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query("select version, applied_at from schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+// migrate applies m if up is true, and reverts it otherwise, in a transaction. It reports false if m has already been
+// applied or reverted, e.g. by another process.
+func migrate(db *sql.DB, m Migration, up bool) (bool, error) {
+	if err := createMigrationTable(db); err != nil {
+		return false, err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("select pg_advisory_xact_lock($1)", migrationLock); err != nil {
+		return false, err
+	}
+	var applied bool
+	if err := tx.QueryRow(
+		"select exists (select 1 from schema_migrations where version = $1)", m.Version).Scan(&applied); err != nil {
+		return false, err
+	}
+	if applied == up {
+		return false, nil
+	}
+
+	if up {
+		if _, err := tx.Exec(m.Up); err != nil {
+			return false, err
+		}
+		_, err = tx.Exec("insert into schema_migrations (version, name) values ($1, $2)", m.Version, m.Name)
+	} else {
+		if _, err := tx.Exec(m.Down); err != nil {
+			return false, err
+		}
+		_, err = tx.Exec("delete from schema_migrations where version = $1", m.Version)
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
@@ -0,0 +1,79 @@
+package pg
+
+import (
+	"testing"
+)
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migration is embedded")
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 || m.Name == "" || m.Up == "" || m.Down == "" {
+			t.Errorf("#%d: got %+v", i, m)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	db := openEmptyDB(t)
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CheckSchema(db); err == nil {
+		t.Error("expected an error for an empty schema, but got nil")
+	}
+	applied, err := MigrateUp(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(applied) != len(migrations) {
+		t.Errorf("got %d migrations applied, want %d", len(applied), len(migrations))
+	}
+	if err := CheckSchema(db); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if applied, err := MigrateUp(db); len(applied) != 0 || err != nil {
+		t.Errorf("got %+v, %v for an up-to-date schema, want no migration applied", applied, err)
+	}
+
+	last := migrations[len(migrations)-1]
+	reverted, err := MigrateDown(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reverted == nil || reverted.Version != last.Version {
+		t.Errorf("got %+v reverted, want %+v", reverted, last)
+	}
+	if err := CheckSchema(db); err == nil {
+		t.Error("expected an error for an out-of-date schema, but got nil")
+	}
+	statuses, err := Status(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, s := range statuses {
+		if pending := s.AppliedAt.IsZero(); pending != (s.Version == last.Version) {
+			t.Errorf("migration %d: got pending %v", s.Version, pending)
+		}
+	}
+
+	// Every migration is reverted, and applied again.
+	for range migrations {
+		if _, err := MigrateDown(db); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if reverted, err := MigrateDown(db); reverted != nil || err != nil {
+		t.Errorf("got %+v, %v for an empty schema, want nil, nil", reverted, err)
+	}
+	if applied, err := MigrateUp(db); len(applied) != len(migrations) || err != nil {
+		t.Errorf("got %d migrations applied, %v, want %d", len(applied), err, len(migrations))
+	}
+}
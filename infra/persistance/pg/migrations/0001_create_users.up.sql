@@ -0,0 +1,10 @@
+-- Users, credentials and sessions are partitioned by tenant_id.
+CREATE TABLE users (
+    tenant_id    text  NOT NULL,
+    -- id is the user handle, which is opaque to the authenticator.
+    id           bytea NOT NULL,
+    name         text  NOT NULL,
+    display_name text  NOT NULL,
+    icon         text  NOT NULL DEFAULT '',
+    PRIMARY KEY (tenant_id, id)
+);
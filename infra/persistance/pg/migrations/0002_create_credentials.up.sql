@@ -0,0 +1,13 @@
+CREATE TABLE credentials (
+    credential_id    bytea  NOT NULL,
+    tenant_id        text   NOT NULL,
+    user_id          bytea  NOT NULL,
+    -- public_key is the COSE_Key encoded credential public key.
+    public_key       bytea  NOT NULL,
+    sign_count       bigint NOT NULL CHECK (sign_count BETWEEN 0 AND 4294967295),
+    attestation_type text   NOT NULL DEFAULT '',
+    CONSTRAINT credentials_credential_id_key UNIQUE (credential_id),
+    FOREIGN KEY (tenant_id, user_id) REFERENCES users (tenant_id, id) ON DELETE CASCADE
+);
+
+CREATE INDEX credentials_user_id_idx ON credentials (tenant_id, user_id);
@@ -0,0 +1,9 @@
+CREATE TABLE sessions (
+    tenant_id     text        NOT NULL,
+    id            text        NOT NULL,
+    user_id       bytea       NOT NULL,
+    challenge     bytea       NOT NULL,
+    last_accessed timestamptz NOT NULL,
+    PRIMARY KEY (tenant_id, id),
+    FOREIGN KEY (tenant_id, user_id) REFERENCES users (tenant_id, id) ON DELETE CASCADE
+);
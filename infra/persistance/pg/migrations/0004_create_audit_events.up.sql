@@ -0,0 +1,14 @@
+-- audit_events records the ceremonies run for the users, e.g. registrations and authentications. The rows outlive the
+-- users and credentials they refer to, so they have no foreign keys.
+CREATE TABLE audit_events (
+    id            bigserial   PRIMARY KEY,
+    tenant_id     text        NOT NULL,
+    user_id       bytea,
+    credential_id bytea,
+    event         text        NOT NULL,
+    -- detail is the event specific data, e.g. the reason a ceremony failed.
+    detail        jsonb       NOT NULL DEFAULT '{}',
+    occurred_at   timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX audit_events_user_id_idx ON audit_events (tenant_id, user_id, occurred_at);
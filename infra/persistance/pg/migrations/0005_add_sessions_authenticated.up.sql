@@ -0,0 +1,3 @@
+-- authenticated records that the user has been authenticated in the session, so that another credential can be
+-- registered with the account.
+ALTER TABLE sessions ADD COLUMN authenticated boolean NOT NULL DEFAULT false;
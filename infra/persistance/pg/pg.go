@@ -0,0 +1,19 @@
+package pg
+
+import (
+	"database/sql"
+)
+
+// scanner is a row of *sql.Row or *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// affected reports whether result affected any row.
+func affected(result sql.Result) (bool, error) {
+	count, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return count != 0, nil
+}
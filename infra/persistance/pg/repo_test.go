@@ -0,0 +1,78 @@
+package pg
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/miliya612/webauthn-demo/infra/persistance/repotest"
+)
+
+// EnvTestDatabaseURL is the connection string of the PostgreSQL database the tests run against. A temporary server is
+// started by TestMain if it is not set, and the tests are skipped only if PostgreSQL is not installed either. Each test
+// creates its own schema in the database, and drops it at the end.
+const EnvTestDatabaseURL = "WEBAUTHN_TEST_DATABASE_URL"
+
+// openTestDB returns a connection to an empty schema of the test database, which every migration is applied to.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db := openEmptyDB(t)
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// openEmptyDB returns a connection to an empty schema of the test database.
+func openEmptyDB(t *testing.T) *sql.DB {
+	t.Helper()
+	url := testDatabaseURL
+	if url == "" {
+		t.Skipf("%v is not set and %v", EnvTestDatabaseURL, errNoPostgres)
+	}
+
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	name := "test_" + hex.EncodeToString(b)
+	admin, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+	if _, err := admin.Exec("create schema " + name); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("drop schema " + name + " cascade"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	// Unknown parameters of the connection string are sent as run-time parameters, e.g. search_path.
+	if strings.Contains(url, "://") {
+		if strings.Contains(url, "?") {
+			url += "&search_path=" + name
+		} else {
+			url += "?search_path=" + name
+		}
+	} else {
+		url += " search_path=" + name
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestRepos(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repos {
+		db := openTestDB(t)
+		return repotest.Repos{Users: NewUserRepo(db), Credentials: NewCredentialRepo(db), Sessions: NewSessionRepo(db)}
+	})
+}
@@ -2,11 +2,10 @@ package pg
 
 import (
 	"database/sql"
-	"fmt"
 	_ "github.com/lib/pq"
+	"github.com/miliya612/webauthn-demo/domain/errUtil"
 	"github.com/miliya612/webauthn-demo/domain/model"
 	"github.com/miliya612/webauthn-demo/domain/repo"
-	"github.com/pkg/errors"
 	"time"
 )
 
@@ -14,34 +13,50 @@ type sessionRepo struct {
 	db *sql.DB
 }
 
-var sessions []*model.Session
-
 func NewSessionRepo(db *sql.DB) repo.SessionRepo {
 	return sessionRepo{db: db}
 }
 
-func (repo sessionRepo) GetByID(id string) (*model.Session, error) {
-	for _, s := range sessions {
-		if id == s.ID {
-			s.LastAccessed = time.Now()
-			return s, nil
-		}
+// GetByID returns the session, and records that it is accessed now.
+func (repo sessionRepo) GetByID(tenantID string, id string) (*model.Session, error) {
+	s := model.Session{}
+	err := repo.db.QueryRow(
+		`update sessions set last_accessed = $3 where tenant_id = $1 and id = $2
+		returning tenant_id, id, user_id, challenge, last_accessed, authenticated`,
+		tenantID, id, time.Now(),
+	).Scan(&s.TenantID, &s.ID, &s.UserID, &s.Challenge, &s.LastAccessed, &s.Authenticated)
+	if err == sql.ErrNoRows {
+		return nil, errUtil.ErrSessionNotFound{}
+	}
+	if err != nil {
+		return nil, err
 	}
-	return nil, errors.New("session not found")
+	return &s, nil
 }
 
 func (repo sessionRepo) Create(session model.Session) (*model.Session, error) {
-	fmt.Println("session: ", session)
-	sessions = append(sessions, &session)
+	_, err := repo.db.Exec(
+		`insert into sessions (tenant_id, id, user_id, challenge, last_accessed, authenticated)
+		values ($1, $2, $3, $4, $5, $6)`,
+		session.TenantID, session.ID, session.UserID, session.Challenge, session.LastAccessed, session.Authenticated,
+	)
+	if err != nil {
+		return nil, err
+	}
 	return &session, nil
 }
 
-func (repo sessionRepo) Delete(id string) (int, error) {
-	for i, s := range sessions {
-		if id == s.ID {
-			sessions = append(sessions[:i], sessions[i+1:]...)
-			return 1, nil
-		}
+func (repo sessionRepo) Delete(tenantID string, id string) (int, error) {
+	result, err := repo.db.Exec("delete from sessions where tenant_id = $1 and id = $2", tenantID, id)
+	if err != nil {
+		return 0, err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, errUtil.ErrSessionNotFound{}
 	}
-	return 0, errors.New("session not found")
+	return int(count), nil
 }
@@ -0,0 +1,366 @@
+package repotest
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miliya612/webauthn-demo/domain/errUtil"
+	"github.com/miliya612/webauthn-demo/domain/model"
+	"github.com/miliya612/webauthn-demo/domain/repo"
+)
+
+// Repos are the repositories of a backend, which share the same storage.
+type Repos struct {
+	Users       repo.UserRepo
+	Credentials repo.CredentialRepo
+	Sessions    repo.SessionRepo
+}
+
+// Open returns the repositories of a backend over empty storage.
+type Open func(t *testing.T) Repos
+
+// Run runs the tests every backend of the repositories has to pass, e.g. in the TestRepos of the backend:
+//
+//	repotest.Run(t, func(t *testing.T) repotest.Repos { ... })
+func Run(t *testing.T, open Open) {
+	t.Run("UserRepo", func(t *testing.T) { testUserRepo(t, open) })
+	t.Run("CredentialRepo", func(t *testing.T) { testCredentialRepo(t, open) })
+	t.Run("SessionRepo", func(t *testing.T) { testSessionRepo(t, open) })
+	t.Run("Copies", func(t *testing.T) { testCopies(t, open) })
+	t.Run("Concurrency", func(t *testing.T) { testConcurrency(t, open) })
+}
+
+func testUserRepo(t *testing.T, open Open) {
+	users := open(t).Users
+	alice := model.User{TenantID: "a", ID: []byte("alice"), Name: "alice", DisplayName: "Alice"}
+
+	if _, err := users.Create(alice); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := users.GetByID("a", alice.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(*got, alice) {
+		t.Errorf("got %+v, want %+v", *got, alice)
+	}
+	if _, err := users.GetByID("b", alice.ID); err != (errUtil.ErrUserNotFound{}) {
+		t.Errorf("got %v for a user of another tenant, want %v", err, errUtil.ErrUserNotFound{})
+	}
+
+	t.Run("Create on an existing user fails", func(t *testing.T) {
+		mallory := alice
+		mallory.DisplayName = "Mallory"
+		if _, err := users.Create(mallory); err != (errUtil.ErrUserConflict{}) {
+			t.Errorf("got %v for an existing user, want %v", err, errUtil.ErrUserConflict{})
+		}
+		if got, _ := users.GetByID("a", alice.ID); got == nil || !reflect.DeepEqual(*got, alice) {
+			t.Errorf("got %+v after creating an existing user, want %+v", got, alice)
+		}
+		if _, err := users.Create(model.User{TenantID: "b", ID: alice.ID, Name: "alice"}); err != nil {
+			t.Errorf("unexpected error for the user handle with another tenant: %v", err)
+		}
+	})
+
+	alice.DisplayName = "Alice Liddell"
+	alice.Icon = "https://example.com/alice.png"
+	if _, err := users.Update(alice); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err = users.GetByID("a", alice.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(*got, alice) {
+		t.Errorf("got %+v, want %+v", *got, alice)
+	}
+
+	if _, err := users.Update(model.User{TenantID: "a", ID: []byte("bob")}); err != (errUtil.ErrUserNotFound{}) {
+		t.Errorf("got %v for an unregistered user, want %v", err, errUtil.ErrUserNotFound{})
+	}
+}
+
+func testCredentialRepo(t *testing.T, open Open) {
+	repos := open(t)
+	users := repos.Users
+	creds := repos.Credentials
+	for _, u := range []model.User{
+		{TenantID: "a", ID: []byte("alice"), Name: "alice"},
+		{TenantID: "b", ID: []byte("alice"), Name: "alice"},
+	} {
+		if _, err := users.Create(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cred := model.Credential{
+		TenantID:        "a",
+		CredentialID:    []byte{1, 2, 3},
+		UserID:          []byte("alice"),
+		PublicKey:       []byte{0xa5},
+		SignCount:       1<<32 - 1,
+		AttestationType: "None",
+	}
+	if _, err := creds.Create(cred); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := creds.GetByCredentialID("a", cred.CredentialID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(*got, cred) {
+		t.Errorf("got %+v, want %+v", *got, cred)
+	}
+	if got, err := creds.GetByCredentialID("b", cred.CredentialID); got != nil || err != nil {
+		t.Errorf("got %+v, %v for a credential of another tenant, want nil, nil", got, err)
+	}
+	list, err := creds.GetByUserID("a", cred.UserID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(list, []model.Credential{cred}) {
+		t.Errorf("got %+v, want %+v", list, []model.Credential{cred})
+	}
+	if list, err := creds.GetByUserID("b", cred.UserID); len(list) != 0 || err != nil {
+		t.Errorf("got %+v, %v for the user of another tenant, want no credential", list, err)
+	}
+
+	cred.SignCount = 2
+	if _, err := creds.Update(cred); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := creds.GetByCredentialID("a", cred.CredentialID); got == nil || got.SignCount != 2 {
+		t.Errorf("got %+v, want the signature counter 2", got)
+	}
+	for _, tt := range []struct {
+		tenantID  string
+		signCount uint32
+		want      bool
+	}{
+		{"a", 3, true},
+		{"a", 3, false},
+		{"a", 1, false},
+		{"b", 4, false},
+	} {
+		if updated, err := creds.UpdateSignCount(tt.tenantID, cred.CredentialID, tt.signCount); updated != tt.want ||
+			err != nil {
+			t.Errorf("got %v, %v for the signature counter %d of tenant %v, want %v, nil",
+				updated, err, tt.signCount, tt.tenantID, tt.want)
+		}
+	}
+	if got, _ := creds.GetByCredentialID("a", cred.CredentialID); got == nil || got.SignCount != 3 {
+		t.Errorf("got %+v, want the signature counter 3", got)
+	}
+	cred.SignCount = 3
+
+	if _, err := creds.Create(cred); err == nil {
+		t.Error("expected an error for a duplicated credential ID, but got nil")
+	}
+	orphan := cred
+	orphan.CredentialID = []byte{4, 5, 6}
+	orphan.UserID = []byte("bob")
+	if _, err := creds.Create(orphan); err == nil {
+		t.Error("expected an error for a credential of an unregistered user, but got nil")
+	}
+
+	if _, err := creds.Delete("b", cred.CredentialID); err != (errUtil.ErrCredentialNotFound{}) {
+		t.Errorf("got %v for a credential of another tenant, want %v", err, errUtil.ErrCredentialNotFound{})
+	}
+	if _, err := creds.Delete("a", cred.CredentialID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, err := creds.GetByCredentialID("a", cred.CredentialID); got != nil || err != nil {
+		t.Errorf("got %+v, %v for a deleted credential, want nil, nil", got, err)
+	}
+	if _, err := creds.Update(cred); err != (errUtil.ErrCredentialNotFound{}) {
+		t.Errorf("got %v for a deleted credential, want %v", err, errUtil.ErrCredentialNotFound{})
+	}
+}
+
+func testSessionRepo(t *testing.T, open Open) {
+	repos := open(t)
+	if _, err := repos.Users.Create(model.User{TenantID: "a", ID: []byte("alice"), Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	sessions := repos.Sessions
+
+	session := model.Session{
+		TenantID:     "a",
+		ID:           "sid",
+		UserID:       []byte("alice"),
+		Challenge:    []byte{1, 2, 3},
+		LastAccessed: time.Now().Add(-time.Hour),
+	}
+	if _, err := sessions.Create(session); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := sessions.GetByID("a", session.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got.LastAccessed.After(session.LastAccessed) {
+		t.Errorf("got the last access at %v, want after %v", got.LastAccessed, session.LastAccessed)
+	}
+	if !reflect.DeepEqual(got.Challenge, session.Challenge) || !reflect.DeepEqual(got.UserID, session.UserID) {
+		t.Errorf("got %+v, want %+v", *got, session)
+	}
+	if _, err := sessions.GetByID("b", session.ID); err != (errUtil.ErrSessionNotFound{}) {
+		t.Errorf("got %v for a session of another tenant, want %v", err, errUtil.ErrSessionNotFound{})
+	}
+
+	if n, err := sessions.Delete("a", session.ID); n != 1 || err != nil {
+		t.Fatalf("got %d, %v, want 1, nil", n, err)
+	}
+	if _, err := sessions.Delete("a", session.ID); err != (errUtil.ErrSessionNotFound{}) {
+		t.Errorf("got %v for a deleted session, want %v", err, errUtil.ErrSessionNotFound{})
+	}
+
+	// The session of an authenticated user has no ceremony running.
+	authenticated := model.Session{TenantID: "a", ID: "sid", UserID: []byte("alice"), Challenge: []byte{},
+		Authenticated: true}
+	if _, err := sessions.Create(authenticated); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, err := sessions.GetByID("a", authenticated.ID); err != nil || !got.Authenticated || len(got.Challenge) != 0 {
+		t.Errorf("got %+v, %v, want an authenticated session without a challenge", got, err)
+	}
+}
+
+// testCopies checks that the values passed to and returned by the repositories do not share their byte slices with
+// the stored ones.
+func testCopies(t *testing.T, open Open) {
+	repos := open(t)
+	user := model.User{TenantID: "a", ID: []byte("alice"), Name: "alice"}
+	if _, err := repos.Users.Create(user); err != nil {
+		t.Fatal(err)
+	}
+	user.ID[0] = 'A'
+	got, err := repos.Users.GetByID("a", []byte("alice"))
+	if err != nil {
+		t.Fatalf("unexpected error after modifying the created user: %v", err)
+	}
+	got.ID[0] = 'A'
+	if _, err := repos.Users.GetByID("a", []byte("alice")); err != nil {
+		t.Fatalf("unexpected error after modifying the returned user: %v", err)
+	}
+
+	cred := model.Credential{TenantID: "a", CredentialID: []byte{1}, UserID: []byte("alice"), PublicKey: []byte{0xa5}}
+	if _, err := repos.Credentials.Create(cred); err != nil {
+		t.Fatal(err)
+	}
+	cred.PublicKey[0] = 0
+	c, err := repos.Credentials.GetByCredentialID("a", []byte{1})
+	if err != nil || c == nil {
+		t.Fatalf("got %+v, %v, want the credential", c, err)
+	}
+	c.PublicKey[0] = 0
+	if c, _ := repos.Credentials.GetByCredentialID("a", []byte{1}); c == nil || c.PublicKey[0] != 0xa5 {
+		t.Errorf("got %+v, want the public key to be unchanged", c)
+	}
+
+	session := model.Session{TenantID: "a", ID: "sid", UserID: []byte("alice"), Challenge: []byte{1, 2, 3}}
+	if _, err := repos.Sessions.Create(session); err != nil {
+		t.Fatal(err)
+	}
+	session.Challenge[0] = 0
+	s, err := repos.Sessions.GetByID("a", "sid")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.Challenge[1] = 0
+	if s, _ := repos.Sessions.GetByID("a", "sid"); s == nil || !reflect.DeepEqual(s.Challenge, []byte{1, 2, 3}) {
+		t.Errorf("got %+v, want the challenge to be unchanged", s)
+	}
+}
+
+// testConcurrency runs ceremonies of several users at once, which is meant to be run with the race detector, e.g.
+// go test -race.
+func testConcurrency(t *testing.T, open Open) {
+	const users, credsPerUser = 8, 4
+	repos := open(t)
+	shared := model.Credential{
+		TenantID:     "a",
+		CredentialID: []byte("shared"),
+		UserID:       []byte("shared"),
+		PublicKey:    []byte{0xa5},
+	}
+	if _, err := repos.Users.Create(model.User{TenantID: "a", ID: shared.UserID, Name: "shared"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.Credentials.Create(shared); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var updates int
+	for i := 0; i < users; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			updated, err := runCeremonies(repos, i, credsPerUser, shared)
+			if err != nil {
+				t.Errorf("user%d: %v", i, err)
+			}
+			if updated {
+				mu.Lock()
+				updates++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Only one of the assertions with the same signature counter is accepted, the others signal a cloned
+	// authenticator.
+	if updates != 1 {
+		t.Errorf("got %d updates of the shared signature counter, want 1", updates)
+	}
+
+	for i := 0; i < users; i++ {
+		creds, err := repos.Credentials.GetByUserID("a", []byte(fmt.Sprintf("user%d", i)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(creds) != credsPerUser {
+			t.Errorf("got %d credentials of user%d, want %d", len(creds), i, credsPerUser)
+		}
+	}
+}
+
+// runCeremonies registers the credentials of the user i, authenticates with each of them, and then with the credential
+// shared by every user with the same signature counter. It returns whether the shared counter has been updated.
+func runCeremonies(repos Repos, i, creds int, shared model.Credential) (bool, error) {
+	userID := []byte(fmt.Sprintf("user%d", i))
+	if _, err := repos.Users.Create(model.User{TenantID: "a", ID: userID, Name: string(userID)}); err != nil {
+		return false, err
+	}
+	for j := 0; j < creds; j++ {
+		sid := fmt.Sprintf("user%d-%d", i, j)
+		session := model.Session{TenantID: "a", ID: sid, UserID: userID, Challenge: []byte(sid)}
+		if _, err := repos.Sessions.Create(session); err != nil {
+			return false, err
+		}
+		cred := model.Credential{TenantID: "a", CredentialID: []byte(sid), UserID: userID, PublicKey: []byte{byte(j)}}
+		if _, err := repos.Credentials.Create(cred); err != nil {
+			return false, err
+		}
+		if _, err := repos.Sessions.GetByID("a", sid); err != nil {
+			return false, err
+		}
+		if _, err := repos.Credentials.UpdateSignCount("a", cred.CredentialID, uint32(j+1)); err != nil {
+			return false, err
+		}
+		if _, err := repos.Sessions.Delete("a", sid); err != nil {
+			return false, err
+		}
+	}
+	if _, err := repos.Credentials.GetByUserID("a", userID); err != nil {
+		return false, err
+	}
+
+	return repos.Credentials.UpdateSignCount(shared.TenantID, shared.CredentialID, shared.SignCount+1)
+}
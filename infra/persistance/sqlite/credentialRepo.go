@@ -0,0 +1,121 @@
+package sqlite
+
+import (
+	"database/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/miliya612/webauthn-demo/domain/errUtil"
+	"github.com/miliya612/webauthn-demo/domain/model"
+	"github.com/miliya612/webauthn-demo/domain/repo"
+)
+
+type credentialRepo struct {
+	db *sql.DB
+}
+
+func NewCredentialRepo(db *sql.DB) repo.CredentialRepo {
+	return credentialRepo{db: db}
+}
+
+const credentialColumns = "tenant_id, credential_id, user_id, public_key, sign_count, attestation_type"
+
+// GetByCredentialID returns nil without an error if the credential is not registered.
+func (repo credentialRepo) GetByCredentialID(tenantID string, id []byte) (*model.Credential, error) {
+	c, err := scanCredential(repo.db.QueryRow(
+		"select "+credentialColumns+" from credentials where tenant_id = ?1 and credential_id = ?2", tenantID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (repo credentialRepo) GetByUserID(tenantID string, userId []byte) ([]model.Credential, error) {
+	rows, err := repo.db.Query(
+		"select "+credentialColumns+" from credentials where tenant_id = ?1 and user_id = ?2 order by credential_id",
+		tenantID, userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var creds []model.Credential
+	for rows.Next() {
+		c, err := scanCredential(rows)
+		if err != nil {
+			return nil, err
+		}
+		creds = append(creds, *c)
+	}
+	return creds, rows.Err()
+}
+
+func (repo credentialRepo) Create(credential model.Credential) (*model.Credential, error) {
+	_, err := repo.db.Exec(
+		"insert into credentials ("+credentialColumns+") values (?1, ?2, ?3, ?4, ?5, ?6)",
+		credential.TenantID, credential.CredentialID, credential.UserID, credential.PublicKey,
+		int64(credential.SignCount), credential.AttestationType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+// Update updates the public key, the signature counter and the attestation type of the credential.
+func (repo credentialRepo) Update(credential model.Credential) (*model.Credential, error) {
+	result, err := repo.db.Exec(
+		`update credentials set public_key = ?3, sign_count = ?4, attestation_type = ?5
+		where tenant_id = ?1 and credential_id = ?2`,
+		credential.TenantID, credential.CredentialID, credential.PublicKey, int64(credential.SignCount),
+		credential.AttestationType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := affected(result); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errUtil.ErrCredentialNotFound{}
+	}
+	return &credential, nil
+}
+
+func (repo credentialRepo) Delete(tenantID string, id []byte) ([]byte, error) {
+	result, err := repo.db.Exec("delete from credentials where tenant_id = ?1 and credential_id = ?2", tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := affected(result); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errUtil.ErrCredentialNotFound{}
+	}
+	return id, nil
+}
+
+// UpdateSignCount updates the signature counter in a single statement, which SQLite runs while it holds the write
+// lock of the database.
+func (repo credentialRepo) UpdateSignCount(tenantID string, id []byte, signCount uint32) (bool, error) {
+	result, err := repo.db.Exec(
+		"update credentials set sign_count = ?3 where tenant_id = ?1 and credential_id = ?2 and sign_count < ?3",
+		tenantID, id, int64(signCount),
+	)
+	if err != nil {
+		return false, err
+	}
+	return affected(result)
+}
+
+func scanCredential(row scanner) (*model.Credential, error) {
+	c := model.Credential{}
+	var signCount int64
+	if err := row.Scan(
+		&c.TenantID, &c.CredentialID, &c.UserID, &c.PublicKey, &signCount, &c.AttestationType); err != nil {
+		return nil, err
+	}
+	c.SignCount = uint32(signCount)
+	return &c, nil
+}
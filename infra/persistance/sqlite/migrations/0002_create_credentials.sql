@@ -0,0 +1,12 @@
+CREATE TABLE credentials (
+    credential_id    blob    NOT NULL UNIQUE,
+    tenant_id        text    NOT NULL,
+    user_id          blob    NOT NULL,
+    -- public_key is the COSE_Key encoded credential public key.
+    public_key       blob    NOT NULL,
+    sign_count       integer NOT NULL CHECK (sign_count BETWEEN 0 AND 4294967295),
+    attestation_type text    NOT NULL DEFAULT '',
+    FOREIGN KEY (tenant_id, user_id) REFERENCES users (tenant_id, id) ON DELETE CASCADE
+);
+
+CREATE INDEX credentials_user_id_idx ON credentials (tenant_id, user_id);
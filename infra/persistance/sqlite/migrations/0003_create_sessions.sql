@@ -0,0 +1,10 @@
+CREATE TABLE sessions (
+    tenant_id     text      NOT NULL,
+    id            text      NOT NULL,
+    user_id       blob      NOT NULL,
+    challenge     blob      NOT NULL,
+    -- last_accessed is declared as timestamp, so that the driver scans it into time.Time.
+    last_accessed timestamp NOT NULL,
+    PRIMARY KEY (tenant_id, id),
+    FOREIGN KEY (tenant_id, user_id) REFERENCES users (tenant_id, id) ON DELETE CASCADE
+);
@@ -0,0 +1,14 @@
+-- audit_events records the ceremonies run for the users, e.g. registrations and authentications. The rows outlive the
+-- users and credentials they refer to, so they have no foreign keys.
+CREATE TABLE audit_events (
+    id            integer   PRIMARY KEY AUTOINCREMENT,
+    tenant_id     text      NOT NULL,
+    user_id       blob,
+    credential_id blob,
+    event         text      NOT NULL,
+    -- detail is the event specific data in JSON, e.g. the reason a ceremony failed.
+    detail        text      NOT NULL DEFAULT '{}',
+    occurred_at   timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX audit_events_user_id_idx ON audit_events (tenant_id, user_id, occurred_at);
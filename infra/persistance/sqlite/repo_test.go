@@ -0,0 +1,51 @@
+package sqlite
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/miliya612/webauthn-demo/infra/persistance/repotest"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := Open(filepath.Join(t.TempDir(), "webauthn.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestRepos(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repos {
+		db := openTestDB(t)
+		return repotest.Repos{Users: NewUserRepo(db), Credentials: NewCredentialRepo(db), Sessions: NewSessionRepo(db)}
+	})
+}
+
+func TestMigrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "webauthn.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var version int
+	if err := db.QueryRow("pragma user_version").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if version == 0 {
+		t.Error("got schema version 0, want the latest version")
+	}
+	// The database of a newer server.
+	if _, err := db.Exec("pragma user_version = 1000"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	if db, err := Open(path); err == nil {
+		db.Close()
+		t.Error("expected an error for a schema newer than the server, but got nil")
+	}
+}
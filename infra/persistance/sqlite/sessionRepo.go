@@ -0,0 +1,62 @@
+package sqlite
+
+import (
+	"database/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/miliya612/webauthn-demo/domain/errUtil"
+	"github.com/miliya612/webauthn-demo/domain/model"
+	"github.com/miliya612/webauthn-demo/domain/repo"
+	"time"
+)
+
+type sessionRepo struct {
+	db *sql.DB
+}
+
+func NewSessionRepo(db *sql.DB) repo.SessionRepo {
+	return sessionRepo{db: db}
+}
+
+// GetByID returns the session, and records that it is accessed now.
+func (repo sessionRepo) GetByID(tenantID string, id string) (*model.Session, error) {
+	s := model.Session{}
+	err := repo.db.QueryRow(
+		`update sessions set last_accessed = ?3 where tenant_id = ?1 and id = ?2
+		returning tenant_id, id, user_id, challenge, last_accessed, authenticated`,
+		tenantID, id, time.Now(),
+	).Scan(&s.TenantID, &s.ID, &s.UserID, &s.Challenge, &s.LastAccessed, &s.Authenticated)
+	if err == sql.ErrNoRows {
+		return nil, errUtil.ErrSessionNotFound{}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (repo sessionRepo) Create(session model.Session) (*model.Session, error) {
+	_, err := repo.db.Exec(
+		`insert into sessions (tenant_id, id, user_id, challenge, last_accessed, authenticated)
+		values (?1, ?2, ?3, ?4, ?5, ?6)`,
+		session.TenantID, session.ID, session.UserID, session.Challenge, session.LastAccessed, session.Authenticated,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (repo sessionRepo) Delete(tenantID string, id string) (int, error) {
+	result, err := repo.db.Exec("delete from sessions where tenant_id = ?1 and id = ?2", tenantID, id)
+	if err != nil {
+		return 0, err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, errUtil.ErrSessionNotFound{}
+	}
+	return int(count), nil
+}
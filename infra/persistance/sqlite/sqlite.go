@@ -0,0 +1,108 @@
+package sqlite
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+	"io/fs"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// migrationFiles are the SQL files of the schema versions, which are named "<version>_<name>.sql", e.g.
+// "0001_create_users.sql". They mirror the migrations of the pg package. Versions start at 1 without gaps.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_\w+\.sql$`)
+
+// Open opens the SQLite database in the file at path, which is created if it does not exist, and migrates its schema
+// to the latest version. Foreign keys are enforced, and the connections are limited to one, since SQLite allows only
+// one writer at a time.
+func Open(path string) (*sql.DB, error) {
+	dsn := "file:" + url.PathEscape(path) + "?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL"
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	if err := Migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Migrate applies the schema versions newer than the version of the database, which is recorded in its user_version.
+// It returns an error if the database is newer than the server.
+func Migrate(db *sql.DB) error {
+	files, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	var current int
+	if err := db.QueryRow("pragma user_version").Scan(&current); err != nil {
+		return err
+	}
+	if current > len(files) {
+		return errors.New(fmt.Sprintf("schema version %d of the database is newer than the server", current))
+	}
+	for i, f := range files {
+		m := migrationFileName.FindStringSubmatch(f[len("migrations/"):])
+		if m == nil {
+			return errors.New(fmt.Sprintf("invalid migration file name: %v", f))
+		}
+		if version, _ := strconv.Atoi(m[1]); version != i+1 {
+			return errors.New(fmt.Sprintf("migration %d is missing", i+1))
+		}
+		if i+1 <= current {
+			continue
+		}
+		b, err := migrationFiles.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		if err := migrate(db, i+1, string(b)); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("unable to apply %v", f))
+		}
+	}
+	return nil
+}
+
+// migrate runs the SQL of version, and records version as the version of the database, in a transaction.
+func migrate(db *sql.DB, version int, migration string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(migration); err != nil {
+		return err
+	}
+	// pragma does not take parameters.
+	if _, err := tx.Exec(fmt.Sprintf("pragma user_version = %d", version)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// scanner is a row of *sql.Row or *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// affected reports whether result affected any row.
+func affected(result sql.Result) (bool, error) {
+	count, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return count != 0, nil
+}
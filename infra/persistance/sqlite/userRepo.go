@@ -0,0 +1,67 @@
+package sqlite
+
+import (
+	"database/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/miliya612/webauthn-demo/domain/errUtil"
+	"github.com/miliya612/webauthn-demo/domain/model"
+	"github.com/miliya612/webauthn-demo/domain/repo"
+)
+
+type userRepo struct {
+	db *sql.DB
+}
+
+func NewUserRepo(db *sql.DB) repo.UserRepo {
+	return userRepo{db: db}
+}
+
+func (repo userRepo) GetByID(tenantID string, id []byte) (*model.User, error) {
+	u := model.User{}
+	err := repo.db.QueryRow(
+		"select tenant_id, id, name, display_name, icon from users where tenant_id = ?1 and id = ?2",
+		tenantID, id,
+	).Scan(&u.TenantID, &u.ID, &u.Name, &u.DisplayName, &u.Icon)
+	if err == sql.ErrNoRows {
+		return nil, errUtil.ErrUserNotFound{}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// Create registers the user account. It returns errUtil.ErrUserConflict if the user handle has been registered with
+// the tenant, so that the account of another user is never overwritten.
+func (repo userRepo) Create(user model.User) (*model.User, error) {
+	result, err := repo.db.Exec(
+		`insert into users (tenant_id, id, name, display_name, icon) values (?1, ?2, ?3, ?4, ?5)
+		on conflict (tenant_id, id) do nothing`,
+		user.TenantID, user.ID, user.Name, user.DisplayName, user.Icon,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := affected(result); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errUtil.ErrUserConflict{}
+	}
+	return &user, nil
+}
+
+func (repo userRepo) Update(user model.User) (*model.User, error) {
+	result, err := repo.db.Exec(
+		"update users set name = ?3, display_name = ?4, icon = ?5 where tenant_id = ?1 and id = ?2",
+		user.TenantID, user.ID, user.Name, user.DisplayName, user.Icon,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := affected(result); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errUtil.ErrUserNotFound{}
+	}
+	return &user, nil
+}
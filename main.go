@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
+	"github.com/miliya612/webauthn-demo/domain/service/metadata"
+	"github.com/miliya612/webauthn-demo/infra/persistance/pg"
 	"github.com/miliya612/webauthn-demo/presentation/routes"
 	"github.com/miliya612/webauthn-demo/registry"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
@@ -20,8 +26,36 @@ func init() {
 }
 
 func main() {
-	r := registry.Registration{}
-	router := routes.NewRouter(r.RegisterCredentialHandler())
+	r := &registry.Registration{}
+	if len(os.Args) > 1 {
+		err := runCommand(r, os.Args[1:])
+		r.Close()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	storage, err := r.RegisterStorage()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if storage == registry.StoragePostgres {
+		db, err := r.RegisterDB()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := pg.CheckSchema(db); err != nil {
+			log.Fatalf("%v, run \"%v migrate up\"", err, os.Args[0])
+		}
+	}
+
+	tenantHandlers, err := r.RegisterTenantHandlers()
+	if err != nil {
+		log.Fatal(err)
+	}
+	router := routes.NewTenantRouter(tenantHandlers)
 	corsMw := mux.CORSMethodMiddleware(router)
 	router.Use(corsMw)
 
@@ -30,6 +64,113 @@ func main() {
 	methodsOk := handlers.AllowedMethods([]string{"GET", "HEAD", "POST", "PUT", "OPTIONS"})
 
 
+	srv := &http.Server{Addr: ":8080", Handler: handlers.CORS(originsOk, headersOk, methodsOk)(router)}
+	go shutdownOnSignal(srv)
+
 	log.Printf("server started at: %v", time.Now())
-	log.Fatal(http.ListenAndServe(":8080", handlers.CORS(originsOk, headersOk, methodsOk)(router)))
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		log.Printf("closing the registry: %v", err)
+	}
+	log.Printf("server stopped at: %v", time.Now())
+}
+
+// shutdownOnSignal stops the server gracefully on SIGINT or SIGTERM, waiting for the requests in flight for a while.
+func shutdownOnSignal(srv *http.Server) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("shutting down the server: %v", err)
+	}
+}
+
+// runCommand runs a maintenance command instead of the server.
+//   - metadata refresh <path>: verifies the FIDO Metadata Service BLOB at path and replaces the cached BLOB with it.
+//   - migrate up: applies the schema migrations which have not been applied to the database.
+//   - migrate down: reverts the last schema migration applied to the database.
+//   - migrate status: lists the schema migrations and whether they have been applied to the database.
+func runCommand(r *registry.Registration, args []string) error {
+	usage := fmt.Errorf("usage: %v metadata refresh <path> | migrate up|down|status", os.Args[0])
+	switch {
+	case len(args) == 3 && args[0] == "metadata" && args[1] == "refresh":
+		return refreshMetadata(r, args[2])
+	case len(args) == 2 && args[0] == "migrate" && (args[1] == "up" || args[1] == "down" || args[1] == "status"):
+		return migrate(r, args[1])
+	default:
+		return usage
+	}
+}
+
+func refreshMetadata(r *registry.Registration, path string) error {
+	roots, err := r.RegisterMetadataRoots()
+	if err != nil {
+		return err
+	}
+	blob, err := metadata.Refresh(path, registry.MetadataBLOBPath, roots, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("metadata BLOB no. %v with %v entries is cached, next update: %v\n",
+		blob.No, len(blob.Entries), blob.NextUpdate)
+	return nil
+}
+
+func migrate(r *registry.Registration, command string) error {
+	storage, err := r.RegisterStorage()
+	if err != nil {
+		return err
+	}
+	if storage != registry.StoragePostgres {
+		return fmt.Errorf("migrate is run for %v storage only, the schema of the others is migrated on start",
+			registry.StoragePostgres)
+	}
+	if r.RegisterDatabaseURL() == "" {
+		return fmt.Errorf("%v is not set", registry.EnvDatabaseURL)
+	}
+	db, err := r.RegisterDB()
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "up":
+		applied, err := pg.MigrateUp(db)
+		for _, m := range applied {
+			fmt.Printf("applied %04d_%v\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+	case "down":
+		m, err := pg.MigrateDown(db)
+		if err != nil {
+			return err
+		}
+		if m == nil {
+			fmt.Println("no migration is applied")
+			return nil
+		}
+		fmt.Printf("reverted %04d_%v\n", m.Version, m.Name)
+	case "status":
+		statuses, err := pg.Status(db)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if !s.AppliedAt.IsZero() {
+				applied = "applied at " + s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%v\t%v\n", s.Version, s.Name, applied)
+		}
+	}
+	return nil
 }
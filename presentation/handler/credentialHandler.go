@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/gorilla/mux"
 	"github.com/miliya612/webauthn-demo/presentation/httputil"
 	"github.com/miliya612/webauthn-demo/presentation/usecase"
 	"github.com/miliya612/webauthn-demo/presentation/usecase/input"
@@ -15,22 +16,36 @@ import (
 type CredentialHandler interface {
 	RegistrationInit(w http.ResponseWriter, r *http.Request)
 	Registration(w http.ResponseWriter, r *http.Request)
-	//AuthenticationInit(w http.ResponseWriter, r *http.Request)
-	//Authentication(w http.ResponseWriter, r *http.Request)
+	AuthenticationInit(w http.ResponseWriter, r *http.Request)
+	Authentication(w http.ResponseWriter, r *http.Request)
+	JWKS(w http.ResponseWriter, r *http.Request)
+	RelatedOrigins(w http.ResponseWriter, r *http.Request)
 }
 
 type credentialHandler struct {
-	registrationInit usecase.RegistrationInitUseCase
-	registration     usecase.RegistrationUseCase
+	registrationInit   usecase.RegistrationInitUseCase
+	registration       usecase.RegistrationUseCase
+	authenticationInit usecase.AuthenticationInitUseCase
+	authentication     usecase.AuthenticationUseCase
+	jwks               usecase.JWKSUseCase
+	relatedOrigins     usecase.RelatedOriginsUseCase
 }
 
 func NewCredentialHandler(
 	registrationInit usecase.RegistrationInitUseCase,
 	registration usecase.RegistrationUseCase,
+	authenticationInit usecase.AuthenticationInitUseCase,
+	authentication usecase.AuthenticationUseCase,
+	jwks usecase.JWKSUseCase,
+	relatedOrigins usecase.RelatedOriginsUseCase,
 ) CredentialHandler {
 	return &credentialHandler{
-		registrationInit: registrationInit,
-		registration:     registration,
+		registrationInit:   registrationInit,
+		registration:       registration,
+		authenticationInit: authenticationInit,
+		authentication:     authentication,
+		jwks:               jwks,
+		relatedOrigins:     relatedOrigins,
 	}
 }
 
@@ -53,6 +68,9 @@ func (h *credentialHandler) RegistrationInit(w http.ResponseWriter, r *http.Requ
 		return
 	}
 	ctx = context.WithValue(ctx, httputil.KeySessionID, uuid)
+	if c, err := r.Cookie(httputil.KeySessionID); err == nil {
+		ctx = context.WithValue(ctx, httputil.KeyLoginSessionID, c.Value)
+	}
 
 	resp, err := h.registrationInit.RegistrationInit(ctx, *in)
 	if err != nil {
@@ -62,7 +80,7 @@ func (h *credentialHandler) RegistrationInit(w http.ResponseWriter, r *http.Requ
 
 	fmt.Println("chal: ", string(resp.PublicKey.Challenge))
 
-	http.SetCookie(w, &http.Cookie{Name: httputil.KeySessionID, Value: uuid})
+	http.SetCookie(w, &http.Cookie{Name: httputil.KeySessionID, Value: uuid, Path: "/"})
 
 	httputil.Created(w, resp)
 }
@@ -80,7 +98,7 @@ func (h *credentialHandler) Registration(w http.ResponseWriter, r *http.Request)
 	}
 
 	ctx, err := getCtxFromSession(r)
-	if err = validateRegistrationRequest(in); err != nil {
+	if err != nil {
 		httputil.Error(w, http.StatusBadRequest, "invalid cookie", err)
 		return
 	}
@@ -93,6 +111,81 @@ func (h *credentialHandler) Registration(w http.ResponseWriter, r *http.Request)
 	httputil.Created(w, resp)
 }
 
+func (h *credentialHandler) AuthenticationInit(w http.ResponseWriter, r *http.Request) {
+	in := parseAuthenticationInitRequest(r)
+
+	if err := validateAuthenticationInitRequest(in); err != nil {
+		httputil.Error(w, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	ctx := r.Context()
+	uuid, err := createUUID()
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, "something went wrong", err)
+		return
+	}
+	ctx = context.WithValue(ctx, httputil.KeySessionID, uuid)
+
+	resp, err := h.authenticationInit.AuthenticationInit(ctx, *in)
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, "failed to start authentication", err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: httputil.KeySessionID, Value: uuid, Path: "/"})
+
+	httputil.Ok(w, resp)
+}
+
+func (h *credentialHandler) Authentication(w http.ResponseWriter, r *http.Request) {
+	in, err := parseAuthenticationRequest(r)
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, "failed to parse request", err)
+		return
+	}
+
+	if err = validateAuthenticationRequest(in); err != nil {
+		httputil.Error(w, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	ctx, err := getCtxFromSession(r)
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, "invalid cookie", err)
+		return
+	}
+
+	resp, err := h.authentication.Authentication(ctx, *in)
+	if err != nil {
+		httputil.Error(w, http.StatusUnauthorized, "authentication failed", err)
+		return
+	}
+	httputil.Ok(w, resp)
+}
+
+func (h *credentialHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	in := &input.JWKS{
+		ID: mux.Vars(r)["name"],
+	}
+
+	resp, err := h.jwks.JWKS(r.Context(), *in)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, "failed to get keys", err)
+		return
+	}
+	httputil.Ok(w, resp)
+}
+
+func (h *credentialHandler) RelatedOrigins(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.relatedOrigins.RelatedOrigins(r.Context())
+	if err != nil {
+		httputil.Error(w, http.StatusNotFound, "no related origins", err)
+		return
+	}
+	httputil.Ok(w, resp)
+}
+
 func parseRegistrationInitRequest(r *http.Request) (*input.RegistrationInit, error) {
 	var in input.RegistrationInit
 	body, err := httputil.ParseBody(r)
@@ -170,6 +263,58 @@ func validateRegistrationRequest(in *input.Registration) error {
 	return nil
 }
 
+func parseAuthenticationInitRequest(r *http.Request) *input.AuthenticationInit {
+	return &input.AuthenticationInit{
+		ID: mux.Vars(r)["name"],
+	}
+}
+
+func validateAuthenticationInitRequest(in *input.AuthenticationInit) error {
+	var invalidParams []string
+	if in.ID == "" {
+		invalidParams = append(invalidParams, "name")
+	}
+	if len(invalidParams) != 0 {
+		return errors.New(fmt.Sprint("required parameters are missing: ", invalidParams))
+	}
+	return nil
+}
+
+func parseAuthenticationRequest(r *http.Request) (*input.Authentication, error) {
+	var in input.Authentication
+
+	body, err := httputil.ParseBody(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(body, &in); err != nil {
+		return nil, errors.New(fmt.Sprint("failed marshalling json", err))
+	}
+
+	return &in, nil
+}
+
+func validateAuthenticationRequest(in *input.Authentication) error {
+	var invalidParams []string
+	if len(in.RawID) == 0 {
+		invalidParams = append(invalidParams, "rawId")
+	}
+	if len(in.Response.ClientDataJSON) == 0 {
+		invalidParams = append(invalidParams, "response.clientDataJSON")
+	}
+	if len(in.Response.AuthenticatorData) == 0 {
+		invalidParams = append(invalidParams, "response.authenticatorData")
+	}
+	if len(in.Response.Signature) == 0 {
+		invalidParams = append(invalidParams, "response.signature")
+	}
+	if len(invalidParams) != 0 {
+		return errors.New(fmt.Sprint("required parameters are missing: ", invalidParams))
+	}
+	return nil
+}
+
 // create a random UUID with from RFC 4122
 // adapted from http://github.com/nu7hatch/gouuid
 func createUUID() (string, error){
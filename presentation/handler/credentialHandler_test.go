@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/miliya612/webauthn-demo/config"
+	"github.com/miliya612/webauthn-demo/domain/model"
+	"github.com/miliya612/webauthn-demo/domain/service"
+	"github.com/miliya612/webauthn-demo/infra/persistance/memory"
+	"github.com/miliya612/webauthn-demo/presentation/usecase"
+)
+
+func TestJWKS(t *testing.T) {
+	db := memory.NewDB()
+	alice := model.User{TenantID: config.DefaultTenantID, ID: []byte("alice"), Name: "alice"}
+	if _, err := memory.NewUserRepo(db).Create(alice); err != nil {
+		t.Fatal(err)
+	}
+	jwks := usecase.NewJWKSUseCase(service.NewCredentialService(memory.NewCredentialRepo(db), config.DefaultTenantID))
+	h := NewCredentialHandler(nil, nil, nil, nil, jwks, nil)
+
+	// A user without credentials is not told apart from an unknown user.
+	for _, name := range []string{"alice", "bob"} {
+		req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/webauthn/users/"+name+"/jwks", nil),
+			map[string]string{"name": name})
+		w := httptest.NewRecorder()
+		h.JWKS(w, req)
+		if w.Code != http.StatusOK || w.Body.String() != `{"keys":[]}` {
+			t.Errorf("%v: got %d %s, want %d %s", name, w.Code, w.Body, http.StatusOK, `{"keys":[]}`)
+		}
+	}
+}
@@ -8,6 +8,10 @@ import (
 
 const KeySessionID = "sid"
 
+// KeyLoginSessionID is the key of the session ID the request has been sent with, whose session records whether the
+// user has been authenticated, when a new session is started by the request.
+const KeyLoginSessionID = "loginSid"
+
 type Manager struct {
 	cookieName  string     //private cookiename
 	lock        sync.Mutex // protects session
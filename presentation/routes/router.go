@@ -2,19 +2,108 @@ package routes
 
 import (
 	"github.com/gorilla/mux"
+	"github.com/miliya612/webauthn-demo/config"
 	"github.com/miliya612/webauthn-demo/presentation/handler"
 	mw "github.com/miliya612/webauthn-demo/presentation/middleware"
+	"sort"
 )
 
 func NewRouter(app handler.AppHandler) *mux.Router {
 
 	router := mux.NewRouter().StrictSlash(true)
+	handleRoutes(router, "", app)
+	return router
+}
+
+// TenantHandler is the handler of the endpoints of a tenant.
+type TenantHandler struct {
+	Tenant  config.Tenant
+	Handler handler.AppHandler
+}
+
+// NewTenantRouter returns a router which routes requests to the handler of the tenant whose hosts and path prefix
+// they match. A tenant matched by both its host and its path prefix takes precedence over one matched by its host
+// only, which in turn takes precedence over one matched by its path prefix only. A tenant with neither receives the
+// rest of requests.
+// The client fetches /.well-known/webauthn from the root of the RP ID, so that it is also served at the root of the
+// hosts of a tenant with a path prefix, unless a tenant without a path prefix is matched by the host.
+func NewTenantRouter(tenants []TenantHandler) *mux.Router {
+	router := mux.NewRouter().StrictSlash(true)
+
+	tenants = append([]TenantHandler{}, tenants...)
+	sort.SliceStable(tenants, func(i, j int) bool {
+		return specificity(tenants[i].Tenant) > specificity(tenants[j].Tenant)
+	})
+	wellKnownHandled := false
+	for _, t := range tenants {
+		// The tenants matched by their hosts only are routed before, so that they serve the roots of their hosts.
+		if !wellKnownHandled && len(t.Tenant.Hosts) == 0 {
+			handleWellKnown(router, tenants)
+			wellKnownHandled = true
+		}
+		hosts := t.Tenant.Hosts
+		if len(hosts) == 0 {
+			hosts = []string{""}
+		}
+		for _, h := range hosts {
+			sub := router.NewRoute()
+			if h != "" {
+				sub = sub.Host(h)
+			}
+			if t.Tenant.PathPrefix != "" {
+				sub = sub.PathPrefix(t.Tenant.PathPrefix)
+			}
+			handleRoutes(sub.Subrouter(), t.Tenant.TenantID+":", t.Handler)
+		}
+	}
+	if !wellKnownHandled {
+		handleWellKnown(router, tenants)
+	}
+	return router
+}
+
+// handleWellKnown registers the related origins of the tenants with both hosts and a path prefix to the roots of
+// their hosts.
+func handleWellKnown(router *mux.Router, tenants []TenantHandler) {
+	for _, t := range tenants {
+		if len(t.Tenant.Hosts) == 0 || t.Tenant.PathPrefix == "" {
+			continue
+		}
+		for _, route := range getRoutes(t.Handler) {
+			if route.Name != relatedOriginsRoute {
+				continue
+			}
+			for _, h := range t.Tenant.Hosts {
+				router.
+					Host(h).
+					Methods(route.Method).
+					Path(route.Path).
+					Name(t.Tenant.TenantID + ":" + h + ":" + route.Name).
+					HandlerFunc(mw.AccessControl(mw.Logging(route.HandlerFunc)))
+			}
+		}
+	}
+}
+
+// specificity ranks how narrowly the requests a tenant receives are matched.
+func specificity(t config.Tenant) int {
+	s := 0
+	if len(t.Hosts) != 0 {
+		s += 2
+	}
+	if t.PathPrefix != "" {
+		s++
+	}
+	return s
+}
+
+// handleRoutes registers the endpoints of app to router, with the route names prefixed by namePrefix.
+func handleRoutes(router *mux.Router, namePrefix string, app handler.AppHandler) {
 	for _, route := range getRoutes(app) {
 		router.
 			Methods(route.Method).
 			Path(route.Path).
-			Name(route.Name).
+			Name(namePrefix + route.Name).
 			HandlerFunc(mw.AccessControl(mw.Logging(route.HandlerFunc)))
 	}
-	return router
 }
@@ -0,0 +1,59 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/miliya612/webauthn-demo/config"
+	"github.com/miliya612/webauthn-demo/presentation/handler"
+)
+
+// tenantHandler responds to the related origins with the ID of its tenant. It embeds handler.AppHandler for the other
+// endpoints, which are not requested.
+type tenantHandler struct {
+	handler.AppHandler
+	tenantID string
+}
+
+func (h tenantHandler) RelatedOrigins(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprint(w, h.tenantID)
+}
+
+func TestNewTenantRouterWellKnown(t *testing.T) {
+	var tenants []TenantHandler
+	for _, tenant := range []config.Tenant{
+		{TenantID: "default"},
+		{TenantID: "demo", PathPrefix: "/demo"},
+		{TenantID: "example", Hosts: []string{"example.com"}, PathPrefix: "/example"},
+		{TenantID: "shop", Hosts: []string{"shop.example.com"}},
+		{TenantID: "shop-admin", Hosts: []string{"shop.example.com"}, PathPrefix: "/admin"},
+	} {
+		tenants = append(tenants, TenantHandler{Tenant: tenant, Handler: tenantHandler{tenantID: tenant.TenantID}})
+	}
+	router := NewTenantRouter(tenants)
+
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{name: "catch-all", url: "http://localhost/.well-known/webauthn", want: "default"},
+		{name: "path prefix", url: "http://localhost/demo/.well-known/webauthn", want: "demo"},
+		{name: "root of host with path prefix", url: "https://example.com/.well-known/webauthn", want: "example"},
+		{name: "host with path prefix", url: "https://example.com/example/.well-known/webauthn", want: "example"},
+		{name: "root of host with port", url: "https://example.com:8443/.well-known/webauthn", want: "example"},
+		{name: "root of host of another tenant", url: "https://shop.example.com/.well-known/webauthn", want: "shop"},
+		{name: "path prefix on shared host", url: "https://shop.example.com/admin/.well-known/webauthn", want: "shop-admin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if w.Code != http.StatusOK || w.Body.String() != tt.want {
+				t.Errorf("got %d %q, want %d %q", w.Code, w.Body, http.StatusOK, tt.want)
+			}
+		})
+	}
+}
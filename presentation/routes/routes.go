@@ -15,12 +15,18 @@ type Route struct {
 
 type Routes []Route
 
+// relatedOriginsRoute is the name of the route of /.well-known/webauthn.
+const relatedOriginsRoute = "RelatedOrigins"
+
 func getRoutes(app handler.AppHandler) []Route {
 	return Routes{
 		Route{"RegistrationInit", "POST", "/attestation/request", app.RegistrationInit},
 		Route{"Registration", "POST", "/attestation/verify", app.Registration},
-		//Route{"AuthenticationInit", "POST", "/credential/authenticate/init", app.TodoCreate},
-		//Route{"Authentication", "POST", "/credential/authenticate", app.TodoDelete},
+		Route{"AuthenticationInit", "POST", "/webauthn/login/start/{name}", app.AuthenticationInit},
+		Route{"Authentication", "POST", "/webauthn/login/finish/{name}", app.Authentication},
+		Route{"JWKS", "GET", "/webauthn/users/{name}/jwks", app.JWKS},
+		// The related origins of the RP ID, which the client fetches from https://{RP ID}/.well-known/webauthn.
+		Route{relatedOriginsRoute, "GET", "/.well-known/webauthn", app.RelatedOrigins},
 		Route{"Index", "GET", "/", index},
 	}
 }
@@ -68,7 +74,8 @@ func index(w http.ResponseWriter, r *http.Request) {
 </div>
 
 	<script type="text/javascript">
-// This is a modification of the example class, where the URLs have been changed to include the name.
+// This is a modification of the example class, where the URLs have been changed to include the name. They are
+// relative to the page, so that a tenant served under a path prefix is sent its own requests.
 class WebAuthn {
 	// Decode a base64 string into a Uint8Array.
 	static _decodeBuffer(value) {
@@ -97,7 +104,7 @@ class WebAuthn {
 		  'Accept': 'application/json',
 		  'Content-Type': 'application/json'
 		};
-		return fetch('/attestation/request', {
+		return fetch('attestation/request', {
 				method: 'POST',
 				headers,
 				body})
@@ -115,7 +122,7 @@ class WebAuthn {
 			})
 			.then(res => navigator.credentials.create(res))
 			.then(credential => {
-				return fetch('/attestation/verify', {
+				return fetch('attestation/verify', {
 					method: 'POST',
 					headers: {
 						'Accept': 'application/json',
@@ -136,7 +143,7 @@ class WebAuthn {
 	}
 
 	login(name) {
-		return fetch('/webauthn/login/start/' + name, {
+		return fetch('webauthn/login/start/' + name, {
 				method: 'POST'
 			})
 			.then(WebAuthn._checkStatus(200))
@@ -152,7 +159,7 @@ class WebAuthn {
 			})
 			.then(res => navigator.credentials.get(res))
 			.then(credential => {
-				return fetch('/webauthn/login/finish/' + name, {
+				return fetch('webauthn/login/finish/' + name, {
 					method: 'POST',
 					headers: {
 						'Accept': 'application/json',
@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"github.com/miliya612/webauthn-demo/domain/service"
+	"github.com/miliya612/webauthn-demo/presentation/httputil"
+	"github.com/miliya612/webauthn-demo/presentation/usecase/input"
+	"github.com/miliya612/webauthn-demo/presentation/usecase/output"
+	"github.com/miliya612/webauthn-demo/webauthnif"
+	"github.com/pkg/errors"
+)
+
+type AuthenticationUseCase interface {
+	Authentication(ctx context.Context, input input.Authentication) (*output.Authentication, error)
+}
+
+type authenticationUseCase struct {
+	authentication service.AuthenticationService
+	session        service.SessionService
+}
+
+func NewAuthenticationUseCase(authentication service.AuthenticationService, session service.SessionService,
+) AuthenticationUseCase {
+	return &authenticationUseCase{
+		authentication: authentication,
+		session:        session,
+	}
+}
+
+// 7.2.
+// Verifying an authentication assertion
+// When verifying a given PublicKeyCredential structure (credential) and an AuthenticationExtensionsClientOutputs
+// structure clientExtensionResults, as part of an authentication ceremony, the Relying Party MUST proceed as follows:
+func (uc authenticationUseCase) Authentication(ctx context.Context, input input.Authentication,
+) (*output.Authentication, error) {
+
+	rawSid := ctx.Value(httputil.KeySessionID)
+	sid := rawSid.(string)
+	session, ok := uc.session.Get(sid)
+	if !ok || len(session.Challenge) == 0 {
+		return nil, errors.New("session is not set")
+	}
+	// The challenge is valid for a single ceremony only.
+	if err := uc.session.Delete(sid); err != nil {
+		return nil, err
+	}
+
+	cred, err := uc.authentication.GetCredential(session.UserID, input.RawID, input.Response.UserHandle)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &webauthnif.DecodedAuthenticatorAssertionResponse{}
+
+	c, err := uc.authentication.ParseClientData(input.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	d.ClientData = *c
+
+	err = uc.authentication.ValidateClientData(session.Challenge, d.ClientData)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err = uc.authentication.ParseAuthenticatorData(input.Response, d)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.authentication.ValidateAuthenticatorData(d.AuthData)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.authentication.ValidateClientExtensionOutputs(input.AuthenticationExtensionsClientOutputs)
+	if err != nil {
+		return nil, err
+	}
+
+	// 16. Let hash be the result of computing a hash over the cData using SHA-256.
+	hashedClientDataJSON := sha256.Sum256(input.Response.ClientDataJSON)
+
+	err = uc.authentication.VerifySignature(*cred, d.RawAuthData, hashedClientDataJSON, input.Response.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.authentication.UpdateSignCount(*cred, d.AuthData.SignCount)
+	if err != nil {
+		return nil, err
+	}
+
+	// 19. If all the above steps are successful, continue with the authentication ceremony as appropriate. Otherwise,
+	// fail the authentication ceremony.
+	user, err := uc.authentication.GetUser(cred.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.session.Authenticate(sid, cred.UserID); err != nil {
+		return nil, err
+	}
+
+	return &output.Authentication{
+		Name: user.Name,
+	}, nil
+}
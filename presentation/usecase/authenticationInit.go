@@ -0,0 +1,48 @@
+package usecase
+
+import (
+	"context"
+	"github.com/miliya612/webauthn-demo/domain/service"
+	"github.com/miliya612/webauthn-demo/presentation/httputil"
+	"github.com/miliya612/webauthn-demo/presentation/usecase/input"
+	"github.com/miliya612/webauthn-demo/presentation/usecase/output"
+	"github.com/miliya612/webauthn-demo/webauthnif"
+)
+
+type AuthenticationInitUseCase interface {
+	AuthenticationInit(ctx context.Context, input input.AuthenticationInit) (*output.AuthenticationInit, error)
+}
+
+type authenticationInitUseCase struct {
+	authentication service.AuthenticationService
+	session        service.SessionService
+}
+
+func NewAuthenticationInitUseCase(authentication service.AuthenticationService, session service.SessionService,
+) AuthenticationInitUseCase {
+	return &authenticationInitUseCase{
+		authentication: authentication,
+		session:        session,
+	}
+}
+
+func (uc authenticationInitUseCase) AuthenticationInit(ctx context.Context, input input.AuthenticationInit,
+) (*output.AuthenticationInit, error) {
+	options, err := uc.authentication.GetOptions(input.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	rawSid := ctx.Value(httputil.KeySessionID)
+	sid := rawSid.(string)
+	err = uc.session.Store(sid, webauthnif.ToBufferSource(input.ID), options.PublicKey.Challenge, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &output.AuthenticationInit{
+		CredentialRequestOptions: webauthnif.CredentialRequestOptions{
+			PublicKey: options.PublicKey,
+		},
+	}, nil
+}
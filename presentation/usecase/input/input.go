@@ -12,3 +12,17 @@ type RegistrationInit struct {
 type Registration struct {
 	webauthnif.PublicKeyCredential
 }
+
+type AuthenticationInit struct {
+	// ID is a identifier
+	ID string `json:"id"`
+}
+
+type Authentication struct {
+	webauthnif.PublicKeyCredentialAssertion
+}
+
+type JWKS struct {
+	// ID is a identifier
+	ID string `json:"id"`
+}
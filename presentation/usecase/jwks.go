@@ -0,0 +1,30 @@
+package usecase
+
+import (
+	"context"
+	"github.com/miliya612/webauthn-demo/domain/service"
+	"github.com/miliya612/webauthn-demo/presentation/usecase/input"
+	"github.com/miliya612/webauthn-demo/presentation/usecase/output"
+)
+
+type JWKSUseCase interface {
+	JWKS(ctx context.Context, input input.JWKS) (*output.JWKS, error)
+}
+
+type jwksUseCase struct {
+	credential service.CredentialService
+}
+
+func NewJWKSUseCase(credential service.CredentialService) JWKSUseCase {
+	return &jwksUseCase{
+		credential: credential,
+	}
+}
+
+func (uc jwksUseCase) JWKS(ctx context.Context, input input.JWKS) (*output.JWKS, error) {
+	jwks, err := uc.credential.GetJWKS(input.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &output.JWKS{JWKS: *jwks}, nil
+}
@@ -1,6 +1,9 @@
 package output
 
-import "github.com/miliya612/webauthn-demo/webauthnif"
+import (
+	"github.com/miliya612/webauthn-demo/cose"
+	"github.com/miliya612/webauthn-demo/webauthnif"
+)
 
 type RegistrationInit struct {
 	webauthnif.CredentialCreationOptions
@@ -8,3 +11,21 @@ type RegistrationInit struct {
 
 type Registration struct {
 }
+
+type AuthenticationInit struct {
+	webauthnif.CredentialRequestOptions
+}
+
+type Authentication struct {
+	// Name is a human-palatable identifier of the authenticated user account.
+	Name string `json:"name"`
+}
+
+type JWKS struct {
+	cose.JWKS
+}
+
+// RelatedOrigins is the document served at /.well-known/webauthn, see "Related Origin Requests" of WebAuthn Level 3.
+type RelatedOrigins struct {
+	Origins []string `json:"origins"`
+}
@@ -38,7 +38,7 @@ func (uc registrationUseCase) Registration(ctx context.Context, input input.Regi
 	rawSid := ctx.Value(httputil.KeySessionID)
 	sid := rawSid.(string)
 	session, ok := uc.session.Get(sid)
-	if !ok {
+	if !ok || len(session.Challenge) == 0 {
 		return nil, errors.New("session is not set")
 	}
 
@@ -74,12 +74,13 @@ func (uc registrationUseCase) Registration(ctx context.Context, input input.Regi
 		return nil, err
 	}
 
-	err = uc.registration.ValidateAttestationResponse(d.DecodedAttestationObject, hashedClientDataJSON)
+	attResult, err := uc.registration.ValidateAttestationResponse(d.DecodedAttestationObject, hashedClientDataJSON)
 	if err != nil {
 		return nil, err
 	}
 
-	err = uc.registration.Register(session.UserID, d.DecodedAttestationObject.AuthData)
+	err = uc.registration.Register(
+		session.UserID, d.DecodedAttestationObject.AuthData, attResult.Type, session.Authenticated)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package usecase
 
 import (
+	"bytes"
 	"context"
 	"github.com/miliya612/webauthn-demo/domain/service"
 	"github.com/miliya612/webauthn-demo/presentation/httputil"
@@ -33,14 +34,15 @@ func (uc registrationInitUseCase) RegistrationInit(ctx context.Context, input in
 		return nil, err
 	}
 	u := options.PublicKey.User
-	err = uc.registration.ReserveClientInfo(u.ID, u.Name, u.DisplayName, u.Icon)
+	authenticated := uc.authenticated(ctx, u.ID)
+	err = uc.registration.ReserveClientInfo(u.ID, u.Name, u.DisplayName, u.Icon, authenticated)
 	if err != nil {
 		return nil, err
 	}
 
 	rawSid := ctx.Value(httputil.KeySessionID)
 	sid := rawSid.(string)
-	err = uc.session.Store(sid, u.ID, options.PublicKey.Challenge)
+	err = uc.session.Store(sid, u.ID, options.PublicKey.Challenge, authenticated)
 	if err != nil {
 		return nil, err
 	}
@@ -51,3 +53,14 @@ func (uc registrationInitUseCase) RegistrationInit(ctx context.Context, input in
 		},
 	}, nil
 }
+
+// authenticated returns whether the request has been sent in the session of the user authenticated as uid, which is
+// required to register another credential with the account.
+func (uc registrationInitUseCase) authenticated(ctx context.Context, uid []byte) bool {
+	sid, ok := ctx.Value(httputil.KeyLoginSessionID).(string)
+	if !ok {
+		return false
+	}
+	session, ok := uc.session.Get(sid)
+	return ok && session.Authenticated && bytes.Equal(session.UserID, uid)
+}
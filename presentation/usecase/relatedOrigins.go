@@ -0,0 +1,33 @@
+package usecase
+
+import (
+	"context"
+	"github.com/miliya612/webauthn-demo/config"
+	"github.com/miliya612/webauthn-demo/presentation/usecase/output"
+	"github.com/pkg/errors"
+)
+
+type RelatedOriginsUseCase interface {
+	RelatedOrigins(ctx context.Context) (*output.RelatedOrigins, error)
+}
+
+type relatedOriginsUseCase struct {
+	rp config.RPConfig
+}
+
+// NewRelatedOriginsUseCase returns a RelatedOriginsUseCase which lists the related origins configured by rp.
+func NewRelatedOriginsUseCase(rp config.RPConfig) RelatedOriginsUseCase {
+	return &relatedOriginsUseCase{
+		rp: rp,
+	}
+}
+
+// RelatedOrigins returns the origins of other domains the credentials of the RP ID may be used on. The origins of the
+// RP ID itself are not listed, since the client accepts them without the document. It returns an error if no related
+// origin is configured.
+func (uc relatedOriginsUseCase) RelatedOrigins(ctx context.Context) (*output.RelatedOrigins, error) {
+	if len(uc.rp.RelatedOrigins) == 0 {
+		return nil, errors.New("no related origin is configured")
+	}
+	return &output.RelatedOrigins{Origins: uc.rp.RelatedOrigins}, nil
+}
@@ -0,0 +1,223 @@
+package registry
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/miliya612/webauthn-demo/config"
+	"github.com/miliya612/webauthn-demo/infra/persistance/memory"
+	"github.com/miliya612/webauthn-demo/webauthnif"
+)
+
+// emptyTrustAnchors replaces the trustanchors directory, which is relative to the working directory of the server.
+type emptyTrustAnchors struct{}
+
+func (emptyTrustAnchors) TrustAnchors(webauthnif.AttestationStatementFormatIdentifier, []byte) (*x509.CertPool, error) {
+	return nil, nil
+}
+
+func testTenants() []config.Tenant {
+	return []config.Tenant{
+		{TenantID: "a", PathPrefix: "/a", RPConfig: config.Default()},
+		{TenantID: "b", PathPrefix: "/b", RPConfig: config.Default()},
+	}
+}
+
+func TestRegisterDB(t *testing.T) {
+	r := &Registration{
+		Tenants:      testTenants(),
+		Storage:      StorageSQLite,
+		DatabaseURL:  filepath.Join(t.TempDir(), "webauthn.db"),
+		TrustAnchors: emptyTrustAnchors{},
+	}
+	if _, err := r.RegisterTenantHandlers(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	db := r.DB
+	if db == nil {
+		t.Fatal("got no database, want the database shared by the tenants")
+	}
+	if got, err := r.RegisterDB(); err != nil || got != db {
+		t.Error("got another database, want the shared one")
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := db.Ping(); err == nil {
+		t.Error("expected the database to be closed, but it is open")
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("unexpected error on closing twice: %v", err)
+	}
+}
+
+func TestRegisterDBOfCaller(t *testing.T) {
+	caller := &Registration{Storage: StorageSQLite, DatabaseURL: filepath.Join(t.TempDir(), "webauthn.db")}
+	defer caller.Close()
+	db, err := caller.RegisterDB()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := &Registration{Storage: StorageSQLite, DB: db}
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := caller.DB.Ping(); err != nil {
+		t.Errorf("got %v, want the database of the caller to be left open", err)
+	}
+}
+
+func TestRegisterRepos(t *testing.T) {
+	db := memory.NewDB()
+	r := &Registration{
+		Tenants: testTenants(),
+		// No database is opened for the storage, since every repository is replaced.
+		Storage:        StoragePostgres,
+		UserRepo:       memory.NewUserRepo(db),
+		CredentialRepo: memory.NewCredentialRepo(db),
+		SessionRepo:    memory.NewSessionRepo(db),
+		TrustAnchors:   emptyTrustAnchors{},
+	}
+	handlers, err := r.RegisterTenantHandlers()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(handlers) != 2 {
+		t.Errorf("got %d handlers, want 2", len(handlers))
+	}
+	if r.DB != nil {
+		t.Error("expected no database to be opened, but got one")
+	}
+}
+
+func TestRegisterPool(t *testing.T) {
+	t.Setenv(EnvMaxOpenConns, "20")
+	t.Setenv(EnvConnMaxIdleTime, "1m")
+	r := &Registration{Pool: Pool{MaxIdleConns: 2}}
+	want := Pool{
+		MaxOpenConns:    20,
+		MaxIdleConns:    2,
+		ConnMaxLifetime: DefaultPool.ConnMaxLifetime,
+		ConnMaxIdleTime: time.Minute,
+	}
+	got, err := r.RegisterPool()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	t.Setenv(EnvMaxOpenConns, "many")
+	if _, err := r.RegisterPool(); err == nil {
+		t.Errorf("expected an error for an invalid %v, but got nil", EnvMaxOpenConns)
+	}
+}
+
+func TestRegisterDBRejects(t *testing.T) {
+	t.Setenv(EnvConnMaxLifetime, "-1m")
+	r := &Registration{
+		Storage:      StoragePostgres,
+		DatabaseURL:  "postgres://localhost/webauthn",
+		TrustAnchors: emptyTrustAnchors{},
+	}
+	if _, err := r.RegisterDB(); err == nil {
+		t.Errorf("expected an error for an invalid %v, but got nil", EnvConnMaxLifetime)
+	}
+	if _, err := r.RegisterTenantHandlers(); err == nil {
+		t.Errorf("expected an error for an invalid %v, but got nil", EnvConnMaxLifetime)
+	}
+
+	r = &Registration{Storage: StorageSQLite, TrustAnchors: emptyTrustAnchors{}}
+	t.Setenv(EnvDatabaseURL, "")
+	if _, err := r.RegisterDB(); err == nil {
+		t.Errorf("expected an error without %v, but got nil", EnvDatabaseURL)
+	}
+}
+
+func TestRegisterAndroidSafetyNetConfig(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test SafetyNet Root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "safetynet.pem")
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tenants := testTenants()
+	tenants[0].RPConfig.AndroidSafetyNet = config.AndroidSafetyNetConfig{Roots: path, MaxTimestampSkew: 30000}
+	r := &Registration{Tenants: tenants}
+	got, err := r.RegisterAndroidSafetyNetConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := x509.NewCertPool()
+	want.AddCert(cert)
+	if got.Roots == nil || !got.Roots.Equal(want) {
+		t.Error("got other roots, want the roots of the configured file")
+	}
+	if got.MaxTimestampSkew != 30*time.Second {
+		t.Errorf("got %v, want %v", got.MaxTimestampSkew, 30*time.Second)
+	}
+	// The roots are read once.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := r.RegisterAndroidSafetyNetConfig(); err != nil || !got.Roots.Equal(want) {
+		t.Errorf("got %v, want the roots read before", err)
+	}
+
+	r = &Registration{Tenants: testTenants()}
+	if got, _ := r.RegisterAndroidSafetyNetConfig(); got.Roots != nil || got.MaxTimestampSkew != time.Minute {
+		t.Errorf("got roots %v and skew %v, want the system roots and %v", got.Roots, got.MaxTimestampSkew, time.Minute)
+	}
+}
+
+func TestRegisterTenantHandlersRejects(t *testing.T) {
+	missingRoots := testTenants()
+	missingRoots[0].RPConfig.AndroidSafetyNet.Roots = filepath.Join(t.TempDir(), "missing.pem")
+
+	tests := []struct {
+		name string
+		r    *Registration
+	}{
+		{name: "unknown storage", r: &Registration{Tenants: testTenants(), Storage: "mysql"}},
+		{name: "missing SafetyNet roots", r: &Registration{Tenants: missingRoots}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.r.TrustAnchors = emptyTrustAnchors{}
+			if _, err := tt.r.RegisterTenantHandlers(); err == nil {
+				t.Error("expected an error, but got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package testutil
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/miliya612/webauthn-demo/webauthnif"
+	"github.com/pkg/errors"
+	"github.com/ugorji/go/codec"
+	"sync"
+)
+
+// Format is the attestation a virtual authenticator conveys on new credentials.
+type Format string
+
+const (
+	// FormatNone conveys no attestation.
+	FormatNone Format = "none"
+	// FormatPackedSelf conveys packed self attestation, which is signed by the credential private key.
+	FormatPackedSelf Format = "packed-self"
+	// FormatPackedFull conveys packed basic attestation, which is signed by an attestation key certified by a CA.
+	FormatPackedFull Format = "packed-full"
+)
+
+const (
+	// DefaultOrigin is the origin virtual authenticators collect client data on, which the demo server accepts.
+	DefaultOrigin = "http://localhost:8080"
+	// credentialIDLength is the byte length of credential IDs virtual authenticators generate.
+	credentialIDLength = 32
+)
+
+// Authenticator is a software authenticator, which holds its credentials in memory. It plays the role of the client
+// as well, i.e. it produces the client data in addition to the authenticator responses.
+type Authenticator struct {
+	// Format is the attestation conveyed on new credentials.
+	Format Format
+	// Origin is the origin the client data is collected on.
+	Origin string
+	// CrossOrigin and TopOrigin are reported in the client data as if the ceremonies were run in an iframe embedded in
+	// the page of TopOrigin.
+	CrossOrigin bool
+	TopOrigin   string
+	// TokenBinding is the state of token binding reported in the client data.
+	TokenBinding *webauthnif.TokenBinding
+	// AAGUID identifies the authenticator model.
+	AAGUID []byte
+	// Flags are set on every authenticator data. AT and ED are ignored, since they are decided by the content of the
+	// authenticator data.
+	Flags webauthnif.AuthenticatorDataFlags
+
+	mu              sync.Mutex
+	credentials     map[string]*credential
+	attestationKey  *ecdsa.PrivateKey
+	attestationCert []byte
+}
+
+// credential is a public key credential source.
+type credential struct {
+	id         []byte
+	rpID       string
+	userHandle []byte
+	key        *ecdsa.PrivateKey
+	signCount  uint32
+}
+
+// NewAuthenticator returns an authenticator which conveys format on new credentials with the UP flag set. ca issues
+// the attestation certificate if format is FormatPackedFull, and is ignored otherwise.
+func NewAuthenticator(format Format, ca *CA) (*Authenticator, error) {
+	a := &Authenticator{
+		Format:      format,
+		Origin:      DefaultOrigin,
+		AAGUID:      make([]byte, 16),
+		Flags:       webauthnif.AuthenticatorDataFlagUserPresent,
+		credentials: make(map[string]*credential),
+	}
+	switch format {
+	case FormatNone, FormatPackedSelf:
+	case FormatPackedFull:
+		if ca == nil {
+			return nil, errors.New("CA is required for packed-full attestation")
+		}
+		if _, err := rand.Read(a.AAGUID); err != nil {
+			return nil, err
+		}
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := ca.IssueAttestationCertificate(&key.PublicKey, a.AAGUID)
+		if err != nil {
+			return nil, err
+		}
+		a.attestationKey = key
+		a.attestationCert = cert
+	default:
+		return nil, errors.New(fmt.Sprintf("unknown format: %q", format))
+	}
+	return a, nil
+}
+
+// MakeCredential emulates navigator.credentials.create() on options. It generates an ES256 credential and returns
+// the PublicKeyCredential the client sends to the Relying Party.
+func (a *Authenticator) MakeCredential(
+	options webauthnif.PublicKeyCredentialCreationOptions) (*webauthnif.PublicKeyCredential, error) {
+	offered := false
+	for _, p := range options.PubKeyCredParams {
+		if p.Alg == webauthnif.COSEAlgorithmIdentifierES256 {
+			offered = true
+		}
+	}
+	if !offered {
+		return nil, errors.New("ES256 is not in pubKeyCredParams")
+	}
+
+	clientDataJSON, err := a.clientDataJSON("webauthn.create", options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	cred := &credential{
+		id:         make([]byte, credentialIDLength),
+		rpID:       options.RP.ID,
+		userHandle: options.User.ID,
+	}
+	if _, err := rand.Read(cred.id); err != nil {
+		return nil, err
+	}
+	if cred.key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		return nil, err
+	}
+	publicKey, err := encodeCBOR(map[int]interface{}{
+		1:  2,
+		3:  int(webauthnif.COSEAlgorithmIdentifierES256),
+		-1: 1,
+		-2: cred.key.X.FillBytes(make([]byte, 32)),
+		-3: cred.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	authData, err := a.authenticatorData(cred, webauthnif.AuthenticatorDataFlagHasCredentialData,
+		webauthnif.AttestedCredentialData{
+			AAGUID:              a.AAGUID,
+			CredentialIdLength:  uint16(len(cred.id)),
+			CredentialID:        cred.id,
+			CredentialPublicKey: publicKey,
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	fmtID, attStmt, err := a.attestationStatement(cred, authData, clientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+	attestationObject, err := encodeCBOR(map[string]interface{}{
+		"fmt":      string(fmtID),
+		"attStmt":  attStmt,
+		"authData": authData,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	a.credentials[string(cred.id)] = cred
+	a.mu.Unlock()
+
+	return &webauthnif.PublicKeyCredential{
+		Credential: webauthnif.Credential{
+			ID:   base64.RawURLEncoding.EncodeToString(cred.id),
+			Type: string(webauthnif.PublicKeyCredentialTypePublicKey),
+		},
+		RawID: cred.id,
+		Response: webauthnif.AuthenticatorAttestationResponse{
+			AuthenticatorResponse: webauthnif.AuthenticatorResponse{ClientDataJSON: clientDataJSON},
+			AttestationObject:     attestationObject,
+		},
+	}, nil
+}
+
+// GetAssertion emulates navigator.credentials.get() on options. It signs with the first credential in
+// allowCredentials the authenticator holds, or with any credential scoped to the RP ID if allowCredentials is empty.
+// The signature counter of the credential is incremented on every assertion.
+func (a *Authenticator) GetAssertion(
+	options webauthnif.PublicKeyCredentialRequest) (*webauthnif.PublicKeyCredentialAssertion, error) {
+	cred, err := a.lookup(options)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataJSON, err := a.clientDataJSON("webauthn.get", options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	cred.signCount++
+	a.mu.Unlock()
+
+	authData, err := a.authenticatorData(cred, 0, webauthnif.AttestedCredentialData{})
+	if err != nil {
+		return nil, err
+	}
+	sig, err := sign(cred.key, authData, clientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webauthnif.PublicKeyCredentialAssertion{
+		Credential: webauthnif.Credential{
+			ID:   base64.RawURLEncoding.EncodeToString(cred.id),
+			Type: string(webauthnif.PublicKeyCredentialTypePublicKey),
+		},
+		RawID: cred.id,
+		Response: webauthnif.AuthenticatorAssertionResponse{
+			AuthenticatorResponse: webauthnif.AuthenticatorResponse{ClientDataJSON: clientDataJSON},
+			AuthenticatorData:     authData,
+			Signature:             sig,
+			UserHandle:            cred.userHandle,
+		},
+	}, nil
+}
+
+// SetSignCount overwrites the signature counter of the credential identified by credentialID, e.g. to emulate a
+// cloned authenticator.
+func (a *Authenticator) SetSignCount(credentialID []byte, signCount uint32) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	cred, ok := a.credentials[string(credentialID)]
+	if !ok {
+		return errors.New("credential is not found")
+	}
+	cred.signCount = signCount
+	return nil
+}
+
+func (a *Authenticator) lookup(options webauthnif.PublicKeyCredentialRequest) (*credential, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, d := range options.AllowCredentials {
+		if cred, ok := a.credentials[string(d.ID)]; ok && cred.rpID == options.RPID {
+			return cred, nil
+		}
+	}
+	if len(options.AllowCredentials) == 0 {
+		for _, cred := range a.credentials {
+			if cred.rpID == options.RPID {
+				return cred, nil
+			}
+		}
+	}
+	return nil, errors.New("no credential is available for the RP")
+}
+
+// clientDataJSON collects the client data of typ on challenge.
+func (a *Authenticator) clientDataJSON(typ string, challenge []byte) ([]byte, error) {
+	return json.Marshal(webauthnif.CollectedClientData{
+		Type:         typ,
+		Challenge:    base64.RawURLEncoding.EncodeToString(challenge),
+		Origin:       a.Origin,
+		CrossOrigin:  a.CrossOrigin,
+		TopOrigin:    a.TopOrigin,
+		TokenBinding: a.TokenBinding,
+	})
+}
+
+// authenticatorData builds the authenticator data of cred with flags in addition to a.Flags.
+func (a *Authenticator) authenticatorData(cred *credential, flags webauthnif.AuthenticatorDataFlags,
+	attested webauthnif.AttestedCredentialData) ([]byte, error) {
+	rpIDHash := sha256.Sum256([]byte(cred.rpID))
+	a.mu.Lock()
+	signCount := cred.signCount
+	a.mu.Unlock()
+	mask := webauthnif.AuthenticatorDataFlags(
+		webauthnif.AuthenticatorDataFlagHasCredentialData | webauthnif.AuthenticatorDataFlagHasExtension)
+	return webauthnif.MarshalAuthenticatorData(&webauthnif.AuthenticatorData{
+		RPIDHash:               rpIDHash[:],
+		Flags:                  a.Flags&^mask | flags,
+		SignCount:              signCount,
+		AttestedCredentialData: attested,
+	})
+}
+
+// attestationStatement returns the attestation statement format identifier and the attestation statement over
+// authData and clientDataJSON.
+func (a *Authenticator) attestationStatement(cred *credential, authData, clientDataJSON []byte) (
+	webauthnif.AttestationStatementFormatIdentifier, map[string]interface{}, error) {
+	alg := int(webauthnif.COSEAlgorithmIdentifierES256)
+	switch a.Format {
+	case FormatPackedSelf:
+		sig, err := sign(cred.key, authData, clientDataJSON)
+		if err != nil {
+			return "", nil, err
+		}
+		return webauthnif.AttestationStatementFormatPacked, map[string]interface{}{"alg": alg, "sig": sig}, nil
+	case FormatPackedFull:
+		sig, err := sign(a.attestationKey, authData, clientDataJSON)
+		if err != nil {
+			return "", nil, err
+		}
+		return webauthnif.AttestationStatementFormatPacked, map[string]interface{}{
+			"alg": alg,
+			"sig": sig,
+			"x5c": []interface{}{a.attestationCert},
+		}, nil
+	}
+	return webauthnif.AttestationStatementFormatNone, map[string]interface{}{}, nil
+}
+
+// sign signs the concatenation of authData and the hash of clientDataJSON with ES256.
+func sign(key *ecdsa.PrivateKey, authData, clientDataJSON []byte) ([]byte, error) {
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(bytes.Join([][]byte{authData, clientDataHash[:]}, nil))
+	return ecdsa.SignASN1(rand.Reader, key, digest[:])
+}
+
+func encodeCBOR(v interface{}) ([]byte, error) {
+	var b []byte
+	cbor := codec.CborHandle{}
+	if err := codec.NewEncoderBytes(&b, &cbor).Encode(v); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
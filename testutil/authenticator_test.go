@@ -0,0 +1,408 @@
+package testutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miliya612/webauthn-demo/config"
+	"github.com/miliya612/webauthn-demo/domain/service/attestation"
+	"github.com/miliya612/webauthn-demo/presentation/routes"
+	"github.com/miliya612/webauthn-demo/registry"
+	"github.com/miliya612/webauthn-demo/webauthnif"
+)
+
+func newServer(t *testing.T, ca *CA, policy attestation.Policy) *httptest.Server {
+	t.Helper()
+	rp := config.Default()
+	rp.AttestationPolicy = policy
+	r := registry.Registration{
+		Tenants:      []config.Tenant{{TenantID: config.DefaultTenantID, RPConfig: rp}},
+		TrustAnchors: ca,
+	}
+	s := httptest.NewServer(newRouter(t, &r))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// newRouter returns the router of the only tenant of r.
+func newRouter(t *testing.T, r *registry.Registration) http.Handler {
+	t.Helper()
+	h, err := r.RegisterCredentialHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return routes.NewRouter(h)
+}
+
+// userName returns a user name unique to the test.
+func userName(t *testing.T) string {
+	return strings.ReplaceAll(t.Name(), "/", "_")
+}
+
+func newCA(t *testing.T) *CA {
+	t.Helper()
+	ca, err := NewCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ca
+}
+
+func newAuthenticator(t *testing.T, format Format, ca *CA) *Authenticator {
+	t.Helper()
+	a, err := NewAuthenticator(format, ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestCeremonies(t *testing.T) {
+	ca := newCA(t)
+
+	tests := []struct {
+		name    string
+		format  Format
+		issuer  *CA
+		policy  attestation.Policy
+		wantErr bool
+	}{
+		{name: "none", format: FormatNone, policy: attestation.PolicyAcceptNone},
+		{name: "packed-self", format: FormatPackedSelf, policy: attestation.PolicyRequireSelf},
+		{name: "packed-full", format: FormatPackedFull, issuer: ca, policy: attestation.PolicyRequireFull},
+		{name: "none under self policy", format: FormatNone, policy: attestation.PolicyRequireSelf, wantErr: true},
+		{
+			name:    "packed-self under full policy",
+			format:  FormatPackedSelf,
+			policy:  attestation.PolicyRequireFull,
+			wantErr: true,
+		},
+		{
+			name:    "packed-full by an untrusted CA",
+			format:  FormatPackedFull,
+			issuer:  newCA(t),
+			policy:  attestation.PolicyRequireFull,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t, ca, tt.policy)
+			c := NewClient(s.URL, newAuthenticator(t, tt.format, tt.issuer))
+			name := userName(t)
+
+			err := c.Register(name)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected an error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := c.Login(name); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRPConfig(t *testing.T) {
+	rp := config.Default()
+	rp.ID = "example.com"
+	rp.Origins = []string{"https://login.example.com"}
+	r := registry.Registration{
+		Tenants:      []config.Tenant{{TenantID: config.DefaultTenantID, RPConfig: rp}},
+		TrustAnchors: newCA(t),
+	}
+	s := httptest.NewServer(newRouter(t, &r))
+	defer s.Close()
+
+	a := newAuthenticator(t, FormatNone, nil)
+	if err := NewClient(s.URL, a).Register(userName(t)); err == nil {
+		t.Fatalf("expected an error for the origin %v, but got nil", a.Origin)
+	}
+
+	a.Origin = "https://login.example.com"
+	c := NewClient(s.URL, a)
+	if err := c.Register(userName(t)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Login(userName(t)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRelatedOrigins(t *testing.T) {
+	rp := config.Default()
+	rp.RelatedOrigins = []string{"https://example.co.jp"}
+	r := registry.Registration{
+		Tenants:      []config.Tenant{{TenantID: config.DefaultTenantID, RPConfig: rp}},
+		TrustAnchors: newCA(t),
+	}
+	s := httptest.NewServer(newRouter(t, &r))
+	defer s.Close()
+
+	res, err := http.Get(s.URL + "/.well-known/webauthn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var got struct {
+		Origins []string `json:"origins"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Origins, rp.RelatedOrigins) {
+		t.Errorf("got %v, want %v", got.Origins, rp.RelatedOrigins)
+	}
+
+	a := newAuthenticator(t, FormatNone, nil)
+	c := NewClient(s.URL, a)
+	if err := c.Register(userName(t)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a.Origin = "https://example.co.jp"
+	if err := c.Login(userName(t)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	a.Origin = "https://example.com"
+	if err := c.Login(userName(t)); err == nil {
+		t.Error("expected an error for an origin which is not related, but got nil")
+	}
+}
+
+func TestClientData(t *testing.T) {
+	rp := config.Default()
+	rp.CrossOrigin = config.CrossOriginPolicyAllowlist
+	rp.TopOrigins = []string{"https://partner.example.org"}
+	r := registry.Registration{
+		Tenants:      []config.Tenant{{TenantID: config.DefaultTenantID, RPConfig: rp}},
+		TrustAnchors: newCA(t),
+	}
+	s := httptest.NewServer(newRouter(t, &r))
+	defer s.Close()
+
+	tests := []struct {
+		name         string
+		crossOrigin  bool
+		topOrigin    string
+		tokenBinding *webauthnif.TokenBinding
+		wantErr      bool
+	}{
+		{name: "iframe of an allowed page", crossOrigin: true, topOrigin: "https://partner.example.org"},
+		{name: "iframe of another page", crossOrigin: true, topOrigin: "https://example.net", wantErr: true},
+		{name: "iframe without top origin", crossOrigin: true, wantErr: true},
+		{
+			name:         "token binding supported",
+			tokenBinding: &webauthnif.TokenBinding{Status: webauthnif.TokenBindingStatusSupported},
+		},
+		{
+			name:         "token binding present",
+			tokenBinding: &webauthnif.TokenBinding{Status: webauthnif.TokenBindingStatusPresent, ID: "AAAA"},
+			wantErr:      true,
+		},
+		{name: "unknown token binding status", tokenBinding: &webauthnif.TokenBinding{Status: "unknown"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAuthenticator(t, FormatNone, nil)
+			a.CrossOrigin = tt.crossOrigin
+			a.TopOrigin = tt.topOrigin
+			a.TokenBinding = tt.tokenBinding
+			err := NewClient(s.URL, a).Register(userName(t))
+			if tt.wantErr && err == nil {
+				t.Error("expected an error, but got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestSQLiteStorage(t *testing.T) {
+	r := registry.Registration{
+		Tenants:      []config.Tenant{{TenantID: config.DefaultTenantID, RPConfig: config.Default()}},
+		TrustAnchors: newCA(t),
+		Storage:      registry.StorageSQLite,
+		DatabaseURL:  filepath.Join(t.TempDir(), "webauthn.db"),
+	}
+	defer r.Close()
+	s := httptest.NewServer(newRouter(t, &r))
+	defer s.Close()
+
+	c := NewClient(s.URL, newAuthenticator(t, FormatNone, nil))
+	if err := c.Register(userName(t)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := c.Login(userName(t)); err != nil {
+			t.Fatalf("#%d: unexpected error: %v", i, err)
+		}
+	}
+}
+
+func TestRegisteredUser(t *testing.T) {
+	s := newServer(t, newCA(t), attestation.PolicyAcceptNone)
+	name := userName(t)
+
+	alice := NewClient(s.URL, newAuthenticator(t, FormatNone, nil))
+	if err := alice.Register(name); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mallory := NewClient(s.URL, newAuthenticator(t, FormatNone, nil))
+	if err := mallory.Register(name); err == nil {
+		t.Fatal("expected an error for the user handle of a registered account, but got nil")
+	}
+	if err := mallory.Login(name); err == nil {
+		t.Error("expected an error for a credential which has not been registered, but got nil")
+	}
+	if err := alice.Login(name); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Another credential is registered in the session the user has been authenticated in.
+	alice.Authenticator = newAuthenticator(t, FormatNone, nil)
+	if err := alice.Register(name); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := alice.Login(name); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := mallory.Register(name); err == nil {
+		t.Error("expected an error for the user handle of a registered account, but got nil")
+	}
+}
+
+func TestSignCount(t *testing.T) {
+	s := newServer(t, newCA(t), attestation.PolicyAcceptNone)
+	a := newAuthenticator(t, FormatNone, nil)
+	c := NewClient(s.URL, a)
+	name := userName(t)
+
+	if err := c.Register(name); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := c.Login(name); err != nil {
+			t.Fatalf("#%d: unexpected error: %v", i, err)
+		}
+	}
+
+	// A clone of the authenticator which has not seen the last assertion.
+	for id := range a.credentials {
+		if err := a.SetSignCount([]byte(id), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Login(name); err == nil {
+		t.Error("expected an error for a signature counter which is not increased, but got nil")
+	}
+}
+
+func TestFlags(t *testing.T) {
+	s := newServer(t, newCA(t), attestation.PolicyAcceptNone)
+
+	tests := []struct {
+		name    string
+		flags   webauthnif.AuthenticatorDataFlags
+		wantErr bool
+	}{
+		{name: "UV", flags: webauthnif.AuthenticatorDataFlagUserPresent | webauthnif.AuthenticatorDataFlagUserVerified},
+		{
+			name: "BE and BS",
+			flags: webauthnif.AuthenticatorDataFlagUserPresent | webauthnif.AuthenticatorDataFlagBackupEligible |
+				webauthnif.AuthenticatorDataFlagBackedUp,
+		},
+		{name: "no UP", flags: 0, wantErr: true},
+		{
+			name:    "BS without BE",
+			flags:   webauthnif.AuthenticatorDataFlagUserPresent | webauthnif.AuthenticatorDataFlagBackedUp,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAuthenticator(t, FormatNone, nil)
+			a.Flags = tt.flags
+			err := NewClient(s.URL, a).Register(userName(t))
+			if tt.wantErr && err == nil {
+				t.Error("expected an error, but got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestTenants(t *testing.T) {
+	ca := newCA(t)
+	example := config.Default()
+	example.ID = "example.com"
+	example.Origins = []string{"https://login.example.com"}
+	example.AttestationPolicy = attestation.PolicyRequireFull
+	r := registry.Registration{
+		Tenants: []config.Tenant{
+			{TenantID: config.DefaultTenantID, RPConfig: config.Default()},
+			{TenantID: "demo", PathPrefix: "/demo", RPConfig: config.Default()},
+			{TenantID: "example", Hosts: []string{"localhost"}, RPConfig: example},
+		},
+		TrustAnchors: ca,
+	}
+	handlers, err := r.RegisterTenantHandlers()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s := httptest.NewServer(routes.NewTenantRouter(handlers))
+	defer s.Close()
+	exampleURL := strings.Replace(s.URL, "127.0.0.1", "localhost", 1)
+	name := userName(t)
+
+	a := newAuthenticator(t, FormatNone, nil)
+	if err := NewClient(s.URL, a).Register(name); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := NewClient(s.URL, a).Login(name); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The credential is scoped to the same RP ID, but the user is not registered with the tenant.
+	if err := NewClient(s.URL+"/demo", a).Login(name); err == nil {
+		t.Fatal("expected an error for a user of another tenant, but got nil")
+	}
+	if err := NewClient(s.URL+"/demo", a).Register(name); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := NewClient(s.URL+"/demo", a).Login(name); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	a = newAuthenticator(t, FormatNone, nil)
+	a.Origin = "https://login.example.com"
+	if err := NewClient(exampleURL, a).Register(name); err == nil {
+		t.Fatal("expected an error for the attestation policy of the tenant, but got nil")
+	}
+	a = newAuthenticator(t, FormatPackedFull, ca)
+	a.Origin = "https://login.example.com"
+	if err := NewClient(exampleURL, a).Register(name); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := NewClient(exampleURL, a).Login(name); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// The client data of the tenant is not accepted by the others.
+	if err := NewClient(s.URL, a).Register(name + "_2"); err == nil {
+		t.Error("expected an error for the origin of another tenant, but got nil")
+	}
+}
@@ -0,0 +1,104 @@
+package testutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"github.com/miliya612/webauthn-demo/webauthnif"
+	"github.com/pkg/errors"
+	"math/big"
+	"time"
+)
+
+// idFidoGenCeAAGUID is the OID of the extension which holds the AAGUID of the authenticator.
+var idFidoGenCeAAGUID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}
+
+// CA is a test certificate authority, which issues attestation certificates to virtual authenticators. It is an
+// attestation.TrustAnchorProvider which trusts its own root certificate only.
+type CA struct {
+	// Certificate is the self-signed root certificate.
+	Certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+}
+
+// NewCA generates a root certificate which is valid for a day.
+func NewCA() (*CA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "webauthn-demo Test Root CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &CA{Certificate: cert, key: key}, nil
+}
+
+// Pool returns a pool which has the root certificate only.
+func (ca *CA) Pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Certificate)
+	return pool
+}
+
+// TrustAnchors returns the root certificate for every authenticator model and attestation statement format.
+func (ca *CA) TrustAnchors(
+	format webauthnif.AttestationStatementFormatIdentifier, aaguid []byte) (*x509.CertPool, error) {
+	return ca.Pool(), nil
+}
+
+// IssueAttestationCertificate issues a DER encoded certificate to pub, which meets the packed attestation statement
+// certificate requirements for the authenticator model identified by aaguid.
+func (ca *CA) IssueAttestationCertificate(pub *ecdsa.PublicKey, aaguid []byte) ([]byte, error) {
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, err
+	}
+	ext, err := asn1.Marshal(aaguid)
+	if err != nil {
+		return nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Country:            []string{"JP"},
+			Organization:       []string{"webauthn-demo"},
+			OrganizationalUnit: []string{"Authenticator Attestation"},
+			CommonName:         "webauthn-demo Virtual Authenticator",
+		},
+		NotBefore:             ca.Certificate.NotBefore,
+		NotAfter:              ca.Certificate.NotAfter,
+		BasicConstraintsValid: true,
+		IsCA:                  false,
+		ExtraExtensions:       []pkix.Extension{{Id: idFidoGenCeAAGUID, Value: ext}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.Certificate, pub, ca.key)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to issue attestation certificate")
+	}
+	return der, nil
+}
+
+func serialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
@@ -0,0 +1,92 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/miliya612/webauthn-demo/webauthnif"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+)
+
+// Client runs the registration and authentication ceremonies against the demo server at URL, in the same way as the
+// script served on the index page, with Authenticator in place of the browser and the security key.
+type Client struct {
+	// URL is the base URL of the server, e.g. the URL of an httptest.Server.
+	URL string
+	// Authenticator creates credentials and assertions.
+	Authenticator *Authenticator
+	http          *http.Client
+}
+
+// NewClient returns a Client which keeps the session cookie between the requests of a ceremony.
+func NewClient(url string, authenticator *Authenticator) *Client {
+	jar, _ := cookiejar.New(nil)
+	return &Client{
+		URL:           url,
+		Authenticator: authenticator,
+		http:          &http.Client{Jar: jar},
+	}
+}
+
+// Register registers a new credential to the user account name.
+func (c *Client) Register(name string) error {
+	var options webauthnif.CredentialCreationOptions
+	body := map[string]string{"id": name, "displayName": name}
+	if err := c.post("/attestation/request", body, http.StatusCreated, &options); err != nil {
+		return err
+	}
+
+	credential, err := c.Authenticator.MakeCredential(options.PublicKey)
+	if err != nil {
+		return err
+	}
+	return c.post("/attestation/verify", credential, http.StatusCreated, nil)
+}
+
+// Login authenticates as the user account name.
+func (c *Client) Login(name string) error {
+	var options webauthnif.CredentialRequestOptions
+	path := "/webauthn/login/start/" + url.PathEscape(name)
+	if err := c.post(path, nil, http.StatusOK, &options); err != nil {
+		return err
+	}
+
+	assertion, err := c.Authenticator.GetAssertion(options.PublicKey)
+	if err != nil {
+		return err
+	}
+	return c.post("/webauthn/login/finish/"+url.PathEscape(name), assertion, http.StatusOK, nil)
+}
+
+// post sends in as JSON to path, and decodes the response into out unless out is nil. It returns an error including
+// the response body if the status code is not status.
+func (c *Client) post(path string, in interface{}, status int, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+	resp, err := c.http.Post(c.URL+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != status {
+		return errors.New(fmt.Sprintf("POST %v: got status %d, want %d: %s", path, resp.StatusCode, status, b))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(b, out)
+}
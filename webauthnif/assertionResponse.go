@@ -0,0 +1,52 @@
+package webauthnif
+
+import "github.com/pkg/errors"
+
+// 5.1
+// PublicKeyCredentialAssertion is a PublicKeyCredential which is created in response to get(). Its response attribute
+// holds an AuthenticatorAssertionResponse instead of an AuthenticatorAttestationResponse.
+type PublicKeyCredentialAssertion struct {
+	Credential
+	// RawID returns the ArrayBuffer contained in the [[identifier]] internal slot.
+	RawID []byte `json:"rawId"`
+	// Response contains the authenticator's response to the client’s request to generate an authentication assertion.
+	Response AuthenticatorAssertionResponse `json:"response"`
+	// AuthenticationExtensionsClientOutputs returns the value of [[clientExtensionsResults]], which is a map containing
+	// extension identifier → client extension output entries produced by the extension’s client extension processing.
+	AuthenticationExtensionsClientOutputs AuthenticationExtensionsClientOutputs `json:"authenticationExtensionsClientOutputs"`
+}
+
+// 5.2.2
+// AuthenticatorAssertionResponse represents an authenticator's response to a client’s request for generation of a new
+// authentication assertion given the WebAuthn Relying Party's challenge and OPTIONAL list of credentials it is aware
+// of. This response contains a cryptographic signature proving possession of the credential private key, and
+// optionally evidence of user consent to a specific transaction.
+// See https://www.w3.org/TR/webauthn/#iface-authenticatorassertionresponse
+type AuthenticatorAssertionResponse struct {
+	AuthenticatorResponse
+	// AuthenticatorData contains the authenticator data returned by the authenticator. See §6.1 Authenticator Data.
+	AuthenticatorData []byte `json:"authenticatorData"`
+	// Signature contains the raw signature returned from the authenticator. See §6.3.3 The authenticatorGetAssertion
+	// Operation.
+	Signature []byte `json:"signature"`
+	// UserHandle contains the user handle returned from the authenticator, or null if the authenticator did not return
+	// a user handle. See §6.3.3 The authenticatorGetAssertion Operation.
+	UserHandle []byte `json:"userHandle"`
+}
+
+// DecodedAuthenticatorAssertionResponse represents the result of parsing the AuthenticatorAssertionResponse
+type DecodedAuthenticatorAssertionResponse struct {
+	DecodedAuthenticatorResponse
+	AuthData    AuthenticatorData
+	RawAuthData []byte
+}
+
+// UnmarshalBinary parses RawAuthData into AuthData. See ParseAuthenticatorData.
+func (a *DecodedAuthenticatorAssertionResponse) UnmarshalBinary() error {
+	data, err := ParseAuthenticatorData(a.RawAuthData)
+	if err != nil {
+		return errors.Wrap(err, "invalid authenticator data")
+	}
+	a.AuthData = *data
+	return nil
+}
@@ -1,5 +1,7 @@
 package webauthnif
 
+import "github.com/miliya612/webauthn-demo/cose"
+
 // 5.2.1
 // AuthenticatorAttestationResponse represents the authenticator's response to a client’s request for the creation of a
 // new public key credential. It contains information about the new credential that can be used to identify it for later
@@ -44,13 +46,43 @@ type DecodedAuthenticatorResponse struct {
 	ClientData CollectedClientData `json:"clientData"`
 }
 
-// CollectedClientData represents
+// 5.10.1
+// CollectedClientData represents the contextual bindings of both the WebAuthn Relying Party and the client.
+// See https://www.w3.org/TR/webauthn/#dictdef-collectedclientdata
 type CollectedClientData struct {
 	Type      string `json:"type"`
 	Challenge string `json:"challenge"`
 	Origin    string `json:"origin"`
+	// CrossOrigin is true if the ceremony was run in an iframe which is not same-origin with its ancestors.
+	CrossOrigin bool `json:"crossOrigin,omitempty"`
+	// TopOrigin is the origin of the top level page the iframe is embedded in. It is present only if CrossOrigin is
+	// true, and only in clients of WebAuthn Level 3.
+	TopOrigin string `json:"topOrigin,omitempty"`
+	// TokenBinding contains information about the state of the Token Binding protocol used when communicating with the
+	// Relying Party. Its absence indicates that the client doesn't support token binding.
+	TokenBinding *TokenBinding `json:"tokenBinding,omitempty"`
+}
+
+// TokenBinding represents the state of the Token Binding protocol on the TLS connection to the Relying Party.
+// See https://www.w3.org/TR/webauthn/#dictdef-tokenbinding
+type TokenBinding struct {
+	Status TokenBindingStatus `json:"status"`
+	// ID is the base64url encoding of the Token Binding ID that was used when communicating with the Relying Party.
+	// It MUST be present if Status is present.
+	ID string `json:"id,omitempty"`
 }
 
+// TokenBindingStatus is the state of the Token Binding protocol.
+type TokenBindingStatus string
+
+const (
+	// TokenBindingStatusPresent indicates token binding was used when communicating with the Relying Party.
+	TokenBindingStatusPresent TokenBindingStatus = "present"
+	// TokenBindingStatusSupported indicates the client supports token binding, but it was not negotiated when
+	// communicating with the Relying Party.
+	TokenBindingStatusSupported TokenBindingStatus = "supported"
+)
+
 // DecodedAttestationObject
 type DecodedAttestationObject struct {
 	Fmt         AttestationStatementFormatIdentifier `json:"fmt"`
@@ -74,6 +106,7 @@ const (
 	AttestationStatementFormatAndroidSafetyNet AttestationStatementFormatIdentifier = "android-safetynet"
 	AttestationStatementFormatFIDOU2F          AttestationStatementFormatIdentifier = "fido-u2f"
 	AttestationStatementFormatNone             AttestationStatementFormatIdentifier = "none"
+	AttestationStatementFormatApple            AttestationStatementFormatIdentifier = "apple"
 )
 
 // 6.1
@@ -96,6 +129,8 @@ type AuthenticatorData struct {
 	SignCount uint32
 	// AttestedCredentialData
 	AttestedCredentialData AttestedCredentialData
+	// Extensions is the authenticator extension outputs, which is nil unless the ED flag is set.
+	Extensions AuthenticationExtensionsAuthenticatorOutputs
 }
 
 type AuthenticatorDataFlags byte
@@ -104,7 +139,11 @@ const (
 	// AuthenticatorDataFlagUserPresent indicates the UP flag.
 	AuthenticatorDataFlagUserPresent = 0x001 // 0000 0001
 	// AuthenticatorDataFlagUserVerified indicates the UV flag.
-	AuthenticatorDataFlagUserVerified = 0x002 // 0000 0010
+	AuthenticatorDataFlagUserVerified = 0x004 // 0000 0100
+	// AuthenticatorDataFlagBackupEligible indicates the BE flag.
+	AuthenticatorDataFlagBackupEligible = 0x008 // 0000 1000
+	// AuthenticatorDataFlagBackedUp indicates the BS flag.
+	AuthenticatorDataFlagBackedUp = 0x010 // 0001 0000
 	// AuthenticatorDataFlagHasCredentialData indicates the AT flag.
 	AuthenticatorDataFlagHasCredentialData = 0x040 // 0100 0000
 	// AuthenticatorDataFlagHasExtension indicates the ED flag.
@@ -121,6 +160,16 @@ func (f AuthenticatorDataFlags) UserVerified() bool {
 	return (f & AuthenticatorDataFlagUserVerified) == AuthenticatorDataFlagUserVerified
 }
 
+// BackupEligible returns whether the BE flag is set, i.e. the credential source may be backed up.
+func (f AuthenticatorDataFlags) BackupEligible() bool {
+	return (f & AuthenticatorDataFlagBackupEligible) == AuthenticatorDataFlagBackupEligible
+}
+
+// BackedUp returns whether the BS flag is set, i.e. the credential source is currently backed up.
+func (f AuthenticatorDataFlags) BackedUp() bool {
+	return (f & AuthenticatorDataFlagBackedUp) == AuthenticatorDataFlagBackedUp
+}
+
 // HasAttestedCredentialData returns whether the AT flag is set.
 func (f AuthenticatorDataFlags) HasAttestedCredentialData() bool {
 	return (f & AuthenticatorDataFlagHasCredentialData) == AuthenticatorDataFlagHasCredentialData
@@ -156,11 +205,9 @@ type AttestedCredentialData struct {
 	// key portion of the credential key pair is known as the credential private key. Note that in the case of self
 	// attestation, the credential key pair is also used as the attestation key pair, see self attestation for details.
 	CredentialPublicKey []byte
-	// TODO: しらべる
-	// credential public keyの要素
-
-	DecodedCredentialPublicKey interface{}
-	// 完成したpublic key
+	// DecodedCredentialPublicKey is CredentialPublicKey decoded and validated, which verifies signatures made by the
+	// credential private key.
+	DecodedCredentialPublicKey cose.Key
 }
 
 type AttestationStatement struct {
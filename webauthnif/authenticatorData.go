@@ -0,0 +1,146 @@
+package webauthnif
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/miliya612/webauthn-demo/cbor"
+	"github.com/miliya612/webauthn-demo/cose"
+	"github.com/pkg/errors"
+	"github.com/ugorji/go/codec"
+)
+
+const (
+	// rpIDHashLength is the byte length of rpIdHash in authenticator data.
+	rpIDHashLength = 32
+	// authDataHeaderLength is the byte length of rpIdHash, flags and signCount, which every authenticator data has.
+	authDataHeaderLength = rpIDHashLength + 1 + 4
+	// aaguidLength is the byte length of aaguid in attested credential data.
+	aaguidLength = 16
+	// maxCredentialIDLength is the maximum of credentialIdLength in attested credential data.
+	maxCredentialIDLength = 1023
+	// cborMajorTypeMap is the major type of CBOR maps, which is the high-order 3 bits of the initial byte.
+	cborMajorTypeMap = 5
+)
+
+// 9
+// AuthenticationExtensionsAuthenticatorOutputs is the extensions of authenticator data, which maps an extension
+// identifier onto its authenticator extension output.
+// See https://www.w3.org/TR/webauthn/#authenticator-extension-output
+type AuthenticationExtensionsAuthenticatorOutputs map[string]interface{}
+
+// ParseAuthenticatorData parses authenticator data returned by both create() and get(). Every length is checked
+// against buf, so that a truncated or padded authenticator data is rejected instead of being partially parsed.
+func ParseAuthenticatorData(buf []byte) (*AuthenticatorData, error) {
+	if len(buf) < authDataHeaderLength {
+		return nil, errors.New(fmt.Sprintf("authenticator data is too short: %d bytes", len(buf)))
+	}
+
+	data := &AuthenticatorData{
+		RPIDHash:  buf[0:rpIDHashLength],
+		Flags:     AuthenticatorDataFlags(buf[rpIDHashLength]),
+		SignCount: binary.BigEndian.Uint32(buf[rpIDHashLength+1 : authDataHeaderLength]),
+	}
+	rest := buf[authDataHeaderLength:]
+
+	// attestedCredentialData is present if and only if the AT flag is set.
+	if data.Flags.HasAttestedCredentialData() {
+		if len(rest) < aaguidLength+2 {
+			return nil, errors.New("attested credential data is too short")
+		}
+		credentialIDLength := binary.BigEndian.Uint16(rest[aaguidLength : aaguidLength+2])
+		if credentialIDLength > maxCredentialIDLength {
+			return nil, errors.New(fmt.Sprintf("credentialIdLength is too long: %d", credentialIDLength))
+		}
+		credentialIDEnd := aaguidLength + 2 + int(credentialIDLength)
+		if len(rest) < credentialIDEnd {
+			return nil, errors.New("credential ID is truncated")
+		}
+
+		// The credential public key has a variable length, so its end is found by walking its CBOR encoding.
+		keyLength, err := cbor.ItemLength(rest[credentialIDEnd:])
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to decode credential public key")
+		}
+		rawKey := rest[credentialIDEnd : credentialIDEnd+keyLength]
+		key, err := cose.Parse(rawKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to parse COSE key")
+		}
+
+		data.AttestedCredentialData = AttestedCredentialData{
+			AAGUID:                     rest[0:aaguidLength],
+			CredentialIdLength:         credentialIDLength,
+			CredentialID:               rest[aaguidLength+2 : credentialIDEnd],
+			CredentialPublicKey:        rawKey,
+			DecodedCredentialPublicKey: key,
+		}
+		rest = rest[credentialIDEnd+keyLength:]
+	}
+
+	// extensions are present if and only if the ED flag is set.
+	if data.Flags.HasExtensions() {
+		extLength, err := cbor.ItemLength(rest)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to decode extensions")
+		}
+		// The extensions have to be a CBOR map, while null and undefined would be decoded into a nil map.
+		if rest[0]>>5 != cborMajorTypeMap {
+			return nil, errors.New(fmt.Sprintf("extensions is not a CBOR map: 0x%02x", rest[0]))
+		}
+		extensions := AuthenticationExtensionsAuthenticatorOutputs{}
+		if err := codec.NewDecoderBytes(rest[:extLength], cbor.NewHandle()).Decode(&extensions); err != nil {
+			return nil, errors.Wrap(err, "extensions is not a map keyed by extension identifiers")
+		}
+		data.Extensions = extensions
+		rest = rest[extLength:]
+	}
+
+	if len(rest) != 0 {
+		return nil, errors.New(fmt.Sprintf("authenticator data has %d leftover bytes", len(rest)))
+	}
+	return data, nil
+}
+
+// MarshalAuthenticatorData encodes data into the byte layout of authenticator data, which is the inverse of
+// ParseAuthenticatorData. Attested credential data and extensions are written only if the AT and ED flags are set,
+// and the credential public key is written as it is in CredentialPublicKey. See §6.1 Authenticator Data.
+func MarshalAuthenticatorData(data *AuthenticatorData) ([]byte, error) {
+	if len(data.RPIDHash) != rpIDHashLength {
+		return nil, errors.New(fmt.Sprintf("rpIdHash must be %d bytes: %d bytes", rpIDHashLength, len(data.RPIDHash)))
+	}
+	buf := make([]byte, 0, authDataHeaderLength)
+	buf = append(buf, data.RPIDHash...)
+	buf = append(buf, byte(data.Flags))
+	buf = binary.BigEndian.AppendUint32(buf, data.SignCount)
+
+	if data.Flags.HasAttestedCredentialData() {
+		cred := data.AttestedCredentialData
+		if len(cred.AAGUID) != aaguidLength {
+			return nil, errors.New(fmt.Sprintf("aaguid must be %d bytes: %d bytes", aaguidLength, len(cred.AAGUID)))
+		}
+		if len(cred.CredentialID) > maxCredentialIDLength {
+			return nil, errors.New(fmt.Sprintf("credential ID is too long: %d bytes", len(cred.CredentialID)))
+		}
+		if len(cred.CredentialPublicKey) == 0 {
+			return nil, errors.New("credential public key is missing")
+		}
+		buf = append(buf, cred.AAGUID...)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(len(cred.CredentialID)))
+		buf = append(buf, cred.CredentialID...)
+		buf = append(buf, cred.CredentialPublicKey...)
+	}
+
+	if data.Flags.HasExtensions() {
+		if data.Extensions == nil {
+			return nil, errors.New("extensions are missing while the ED flag is set")
+		}
+		var ext []byte
+		h := codec.CborHandle{}
+		h.Canonical = true
+		if err := codec.NewEncoderBytes(&ext, &h).Encode(data.Extensions); err != nil {
+			return nil, errors.Wrap(err, "unable to encode extensions")
+		}
+		buf = append(buf, ext...)
+	}
+	return buf, nil
+}
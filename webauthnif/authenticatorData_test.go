@@ -0,0 +1,188 @@
+package webauthnif
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/binary"
+	"math/big"
+	mathrand "math/rand"
+	"testing"
+
+	"github.com/ugorji/go/codec"
+)
+
+func encodeCBOR(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	var b []byte
+	if err := codec.NewEncoderBytes(&b, &codec.CborHandle{}).Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// authData builds authenticator data with flags, followed by attested credential data if credentialID is not nil,
+// and by extensions if it is not nil.
+func authData(t *testing.T, flags byte, credentialID []byte, extensions map[string]interface{}) []byte {
+	t.Helper()
+	b := append(make([]byte, 32), flags, 0, 0, 0, 7)
+	if credentialID != nil {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		b = append(b, make([]byte, 16)...)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(credentialID)))
+		b = append(b, credentialID...)
+		b = append(b, encodeCBOR(t, map[int]interface{}{
+			1:  2,
+			3:  -7,
+			-1: 1,
+			-2: key.X.FillBytes(make([]byte, 32)),
+			-3: key.Y.FillBytes(make([]byte, 32)),
+		})...)
+	}
+	if extensions != nil {
+		b = append(b, encodeCBOR(t, extensions)...)
+	}
+	return b
+}
+
+func TestParseAuthenticatorData(t *testing.T) {
+	credentialID := []byte("credential-id-0123456789")
+	extensions := map[string]interface{}{"credProtect": 2}
+
+	t.Run("assertion", func(t *testing.T) {
+		data, err := ParseAuthenticatorData(authData(t, 0x01, nil, nil))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if data.SignCount != 7 || !data.Flags.UserPresent() {
+			t.Errorf("unexpected header: %+v", data)
+		}
+	})
+
+	t.Run("attested credential data and extensions", func(t *testing.T) {
+		data, err := ParseAuthenticatorData(authData(t, 0xc1, credentialID, extensions))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Equal(data.AttestedCredentialData.CredentialID, credentialID) {
+			t.Errorf("got credential ID %x, want %x", data.AttestedCredentialData.CredentialID, credentialID)
+		}
+		if data.AttestedCredentialData.DecodedCredentialPublicKey == nil {
+			t.Error("credential public key is not decoded")
+		}
+		if _, ok := data.Extensions["credProtect"]; !ok {
+			t.Errorf("credProtect is missing in extensions: %v", data.Extensions)
+		}
+	})
+}
+
+func TestParseAuthenticatorDataRejects(t *testing.T) {
+	credentialID := []byte("credential-id-0123456789")
+	valid := authData(t, 0x41, credentialID, nil)
+
+	tests := []struct {
+		name string
+		buf  []byte
+	}{
+		{name: "empty", buf: nil},
+		{name: "header is truncated", buf: valid[:36]},
+		{name: "AT flag without attested credential data", buf: valid[:37]},
+		{name: "credential ID is truncated", buf: valid[:55+len(credentialID)-1]},
+		{name: "credential public key is truncated", buf: valid[:len(valid)-1]},
+		{name: "leftover bytes", buf: append(append([]byte{}, valid...), 0x00)},
+		{name: "extensions without ED flag", buf: authData(t, 0x01, nil, map[string]interface{}{"credProtect": 2})},
+		{name: "ED flag without extensions", buf: authData(t, 0x81, nil, nil)},
+		{name: "credentialIdLength exceeds the maximum", buf: func() []byte {
+			b := append([]byte{}, valid...)
+			binary.BigEndian.PutUint16(b[53:55], 1024)
+			return b
+		}()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseAuthenticatorData(tt.buf); err == nil {
+				t.Error("expected an error, but got nil")
+			}
+		})
+	}
+}
+
+// TestMarshalAuthenticatorDataRoundTrip checks that synthetic authenticator data survives being encoded and parsed
+// back, for random combinations of flags, credential IDs, key types and extensions.
+func TestMarshalAuthenticatorDataRoundTrip(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keys := [][]byte{
+		encodeCBOR(t, map[int]interface{}{
+			1:  2,
+			3:  -7,
+			-1: 1,
+			-2: ecKey.X.FillBytes(make([]byte, 32)),
+			-3: ecKey.Y.FillBytes(make([]byte, 32)),
+		}),
+		encodeCBOR(t, map[int]interface{}{1: 1, 3: -8, -1: 6, -2: []byte(edPub)}),
+		encodeCBOR(t, map[int]interface{}{1: 3, 3: -257, -1: rsaKey.N.Bytes(), -2: big.NewInt(int64(rsaKey.E)).Bytes()}),
+	}
+	extensionValues := []interface{}{uint64(2), true, "value", []byte{0x01, 0x02}}
+
+	r := mathrand.New(mathrand.NewSource(1))
+	random := func(n int) []byte {
+		b := make([]byte, n)
+		r.Read(b)
+		return b
+	}
+
+	for i := 0; i < 500; i++ {
+		want := &AuthenticatorData{
+			RPIDHash:  random(rpIDHashLength),
+			Flags:     AuthenticatorDataFlags(r.Intn(256)),
+			SignCount: r.Uint32(),
+		}
+		if want.Flags.HasAttestedCredentialData() {
+			credentialID := random(r.Intn(maxCredentialIDLength + 1))
+			want.AttestedCredentialData = AttestedCredentialData{
+				AAGUID:              random(aaguidLength),
+				CredentialIdLength:  uint16(len(credentialID)),
+				CredentialID:        credentialID,
+				CredentialPublicKey: keys[r.Intn(len(keys))],
+			}
+		}
+		if want.Flags.HasExtensions() {
+			want.Extensions = AuthenticationExtensionsAuthenticatorOutputs{}
+			for j := r.Intn(4); j >= 0; j-- {
+				want.Extensions[string(rune('a'+r.Intn(26)))] = extensionValues[r.Intn(len(extensionValues))]
+			}
+		}
+
+		b, err := MarshalAuthenticatorData(want)
+		if err != nil {
+			t.Fatalf("#%d: unexpected error: %v", i, err)
+		}
+		got, err := ParseAuthenticatorData(b)
+		if err != nil {
+			t.Fatalf("#%d: unexpected error: %v: %x", i, err, b)
+		}
+
+		if !bytes.Equal(got.RPIDHash, want.RPIDHash) || got.Flags != want.Flags || got.SignCount != want.SignCount {
+			t.Errorf("#%d: got header %x %x %d, want %x %x %d", i,
+				got.RPIDHash, got.Flags, got.SignCount, want.RPIDHash, want.Flags, want.SignCount)
+		}
+		gotCred, wantCred := got.AttestedCredentialData, want.AttestedCredentialData
+		if !bytes.Equal(gotCred.AAGUID, wantCred.AAGUID) ||
+			!bytes.Equal(gotCred.CredentialID, wantCred.CredentialID) ||
+			!bytes.Equal(gotCred.CredentialPublicKey, wantCred.CredentialPublicKey) {
+			t.Errorf("#%d: got attested credential data %+v, want %+v", i, gotCred, wantCred)
+		}
+		if len(got.Extensions) != len(want.Extensions) {
+			t.Errorf("#%d: got extensions %v, want %v", i, got.Extensions, want.Extensions)
+		}
+		if again, err := MarshalAuthenticatorData(got); err != nil || !bytes.Equal(again, b) {
+			t.Errorf("#%d: parsed authenticator data is not encoded as it was: %v", i, err)
+		}
+	}
+}
@@ -212,8 +212,20 @@ const (
 type COSEAlgorithmIdentifier int
 
 const (
-	COSEAlgorithmIdentifierES256 COSEAlgorithmIdentifier = -7
-	COSEAlgorithmIdentifierRS256 COSEAlgorithmIdentifier = -257
+	COSEAlgorithmIdentifierES256  COSEAlgorithmIdentifier = -7
+	COSEAlgorithmIdentifierEdDSA  COSEAlgorithmIdentifier = -8
+	COSEAlgorithmIdentifierES384  COSEAlgorithmIdentifier = -35
+	COSEAlgorithmIdentifierES512  COSEAlgorithmIdentifier = -36
+	COSEAlgorithmIdentifierPS256  COSEAlgorithmIdentifier = -37
+	COSEAlgorithmIdentifierPS384  COSEAlgorithmIdentifier = -38
+	COSEAlgorithmIdentifierPS512  COSEAlgorithmIdentifier = -39
+	COSEAlgorithmIdentifierES256K COSEAlgorithmIdentifier = -47
+	COSEAlgorithmIdentifierRS256  COSEAlgorithmIdentifier = -257
+	COSEAlgorithmIdentifierRS384  COSEAlgorithmIdentifier = -258
+	COSEAlgorithmIdentifierRS512  COSEAlgorithmIdentifier = -259
+	// COSEAlgorithmIdentifierRS1 is RSASSA-PKCS1-v1_5 with SHA-1, which is not acceptable for credentials but is used
+	// by TPMs, e.g. of Windows Hello, to sign certInfo of tpm attestation statements.
+	COSEAlgorithmIdentifierRS1 COSEAlgorithmIdentifier = -65535
 )
 
 // 5.10.4
@@ -26,3 +26,11 @@ type PublicKeyCredentialRequest struct {
 	// included as an extension.
 	Extensions       AuthenticationExtensionsClientInputs `json:"extensions"`
 }
+
+// 5.1.2
+// CredentialRequestOptions is an extension of the CredentialRequestOptions dictionary in order to support obtaining
+// assertions via navigator.credentials.get()
+// See https://www.w3.org/TR/webauthn/#credentialrequestoptions-extension
+type CredentialRequestOptions struct {
+	PublicKey PublicKeyCredentialRequest `json:"publicKey"`
+}
@@ -0,0 +1,120 @@
+package webauthnif
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"os"
+	"strings"
+	"testing"
+)
+
+// realAttestationObjects reads testdata/attestationObjects.txt, which has attestation objects returned by real
+// authenticators.
+func realAttestationObjects(tb testing.TB) [][]byte {
+	tb.Helper()
+	f, err := os.Open("testdata/attestationObjects.txt")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer f.Close()
+
+	var objs [][]byte
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		b, err := base64.RawURLEncoding.DecodeString(line)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		objs = append(objs, b)
+	}
+	if err := s.Err(); err != nil {
+		tb.Fatal(err)
+	}
+	return objs
+}
+
+func TestParseAttestationObjectOfRealAuthenticators(t *testing.T) {
+	for i, b := range realAttestationObjects(t) {
+		obj, err := ParseAttestationObject(b)
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+			continue
+		}
+		if obj.AuthData.AttestedCredentialData.DecodedCredentialPublicKey == nil {
+			t.Errorf("#%d: credential public key is not decoded", i)
+		}
+	}
+}
+
+func FuzzParseAttestationObject(f *testing.F) {
+	for _, b := range realAttestationObjects(f) {
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		obj, err := ParseAttestationObject(b)
+		if err != nil {
+			return
+		}
+		if obj.Fmt == "" {
+			t.Error("fmt is empty")
+		}
+		if _, err := ParseAuthenticatorData(obj.RawAuthData); err != nil {
+			t.Errorf("authData is accepted once, but rejected on reparsing: %v", err)
+		}
+	})
+}
+
+func FuzzUnmarshalBinary(f *testing.F) {
+	for _, b := range realAttestationObjects(f) {
+		obj, err := ParseAttestationObject(b)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(obj.RawAuthData)
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		obj := DecodedAttestationObject{RawAuthData: b}
+		if err := obj.UnmarshalBinary(); err != nil {
+			return
+		}
+		// Accepted authenticator data must be reproduced byte by byte, or something in it has been ignored.
+		got, err := MarshalAuthenticatorData(&obj.AuthData)
+		if err != nil {
+			t.Fatalf("unable to marshal accepted authenticator data: %v", err)
+		}
+		if obj.AuthData.Flags.HasExtensions() {
+			// Extensions are not necessarily encoded canonically by authenticators.
+			return
+		}
+		if !bytes.Equal(got, b) {
+			t.Errorf("got %x, want %x", got, b)
+		}
+	})
+}
+
+func FuzzParseCOSE(f *testing.F) {
+	for _, b := range realAttestationObjects(f) {
+		obj, err := ParseAttestationObject(b)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(obj.AuthData.AttestedCredentialData.CredentialPublicKey)
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		key, err := ParseCOSE(b)
+		if err != nil {
+			return
+		}
+		// ParseCOSE validates a key, so that an accepted key is always usable.
+		if _, err := key.PublicKey(); err != nil {
+			t.Errorf("accepted key has no public key: %v", err)
+		}
+		_ = key.Verify([]byte("data"), []byte("signature"))
+	})
+}
@@ -2,8 +2,9 @@ package webauthnif
 
 import (
 	"bytes"
-	"encoding/binary"
 	"fmt"
+	"github.com/miliya612/webauthn-demo/cbor"
+	"github.com/miliya612/webauthn-demo/cose"
 	"github.com/pkg/errors"
 	"github.com/ugorji/go/codec"
 	"math/rand"
@@ -30,84 +31,41 @@ func (bf BufferSource) Equals(abf BufferSource) bool {
 	return bytes.Equal(([]byte)(bf), ([]byte)(abf))
 }
 
-// UnmarshalBinary
-func (a *DecodedAttestationObject) UnmarshalBinary() error {
-	if len(a.RawAuthData) < 37 {
-		return errors.New("invalid authenticator data")
+// ParseAttestationObject performs CBOR decoding on attestationObject to obtain the attestation statement format fmt,
+// the authenticator data authData, and the attestation statement attStmt, and then parses authData.
+func ParseAttestationObject(attestationObject []byte) (*DecodedAttestationObject, error) {
+	if _, err := cbor.ItemLength(attestationObject); err != nil {
+		return nil, errors.Wrap(err, "attestation object is not a CBOR map")
 	}
-
-	a.AuthData.RPIDHash = a.RawAuthData[0:32]
-	a.AuthData.Flags = AuthenticatorDataFlags(a.RawAuthData[32])
-	a.AuthData.SignCount = binary.BigEndian.Uint32(a.RawAuthData[33:37])
-
-	if a.AuthData.Flags.HasAttestedCredentialData() && len(a.RawAuthData) > 37 {
-		credentialIDLen := binary.BigEndian.Uint16(a.RawAuthData[53:55])
-		dPubKey, err := ParseCOSE(a.RawAuthData[55+credentialIDLen:])
-		if err != nil {
-			return errors.New(fmt.Sprintf("unable to parse COSE key: %v", err.Error()))
-		}
-
-		attestedCredentialData := AttestedCredentialData{
-			AAGUID:                     a.RawAuthData[37:53],
-			CredentialIdLength:         credentialIDLen,
-			CredentialID:               a.RawAuthData[55 : 55+credentialIDLen],
-			CredentialPublicKey:        a.RawAuthData[55+credentialIDLen:],
-			DecodedCredentialPublicKey: dPubKey,
-		}
-
-		a.AuthData.AttestedCredentialData = attestedCredentialData
-
+	obj := &DecodedAttestationObject{}
+	d := codec.NewDecoderBytes(attestationObject, cbor.NewHandle())
+	if err := d.Decode(obj); err != nil {
+		return nil, errors.Wrap(err, "attestation object is not a CBOR map")
 	}
-
-	return nil
-}
-
-// ParseCOSE parses a raw COSE key into a public key
-func ParseCOSE(buf []byte) (interface{}, error) {
-	m := make(map[int]interface{})
-
-	cbor := codec.CborHandle{}
-
-	if err := codec.NewDecoder(bytes.NewReader(buf), &cbor).Decode(&m); err != nil {
+	if d.NumBytesRead() != len(attestationObject) {
+		return nil, errors.New(fmt.Sprintf("attestation object has %d leftover bytes",
+			len(attestationObject)-d.NumBytesRead()))
+	}
+	if obj.Fmt == "" {
+		return nil, errors.New("fmt is missing in attestation object")
+	}
+	if err := obj.UnmarshalBinary(); err != nil {
 		return nil, err
 	}
-
-	return m, nil
+	return obj, nil
 }
 
-func ParseCOSEMap(m map[int]interface{}) (interface{}, error) {
-	err := validateCOSEMap(m)
+// UnmarshalBinary parses RawAuthData into AuthData. See ParseAuthenticatorData.
+func (a *DecodedAttestationObject) UnmarshalBinary() error {
+	data, err := ParseAuthenticatorData(a.RawAuthData)
 	if err != nil {
-		return nil, errors.Wrap(err, "invalid COSE map")
+		return errors.Wrap(err, "invalid authenticator data")
 	}
-	return nil, nil
+	a.AuthData = *data
+	return nil
 }
 
-func validateCOSEMap(m map[int]interface{}) error {
-	/*
-		{
-		  1: kty=2,  // EC2 key type
-		  3: alg=-7, // ES256 signature algorithm
-		 -1: crv=1,  // P-256 curve
-		 -2: x,      // x-coordinate as byte string 32 bytes in length
-		 -3: y,      // y-coordinate as byte string 32 bytes in length
-		}
-	 */
-
-	// The credential public key encoded in COSE_Key format, as defined in Section 7 of [RFC8152], using the CTAP2
-	// canonical CBOR encoding form. The COSE_Key-encoded credential public key MUST contain the "alg" parameter and
-	// MUST NOT contain any other OPTIONAL parameters. The "alg" parameter MUST contain a COSEAlgorithmIdentifier value.
-	// The encoded credential public key MUST also contain any additional REQUIRED parameters stipulated by the
-	// relevant key type specification, i.e., REQUIRED for the key type "kty" and algorithm "alg" (see Section 8 of
-	// [RFC8152]).
-
-	//if kty, ok := m[1]; !ok {
-	//	return errors.New("missing ec2 key type")
-	//}
-	//
-	//if alg, ok := m[3]; !ok {
-	//	return errors.New("missing es256 signature algorithm")
-	//}
-
-	return nil
+// ParseCOSE parses a raw COSE key into a typed public key
+func ParseCOSE(buf []byte) (cose.Key, error) {
+	return cose.Parse(buf)
 }